@@ -46,7 +46,7 @@ func main() {
 	logger.Infof("Manifest digest: %s\n", manifest.Config.Digest.String())
 	for state := range stateChan {
 		print("\033[2J")
-		logger.Infof("%s", state.Status())
+		logger.Infof("%s: %s", state.Status(), state.Progress().String())
 		for idx, l := range state.Layers() {
 			logger.Infof("%02d [%s]: %s", idx, l.Id(), l.Status())
 		}
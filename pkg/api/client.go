@@ -8,13 +8,25 @@ import (
 
 	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder"
+	buildevents "github.com/silenium-dev/docker-wrapper/pkg/client/builder/events"
+	buildstate "github.com/silenium-dev/docker-wrapper/pkg/client/builder/state"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/exec"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/hostip"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/inspect"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/logs"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/managed"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/provider"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/state"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/wait"
 	"go.uber.org/zap"
 )
 
@@ -29,24 +41,53 @@ type ImageClient interface {
 	ImageBuild(
 		ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions,
 	) (build.ImageBuildResponse, error)
-	ImagePullWithEvents(ctx context.Context, ref reference.Named, options image.PullOptions) (
+	ImageBuildWithEvents(
+		ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions, buildOpts ...builder.Opt,
+	) (chan buildevents.BuildEvent, error)
+	ImageBuildWithState(
+		ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions, buildOpts ...builder.Opt,
+	) (chan buildstate.Build, error)
+	ImagePullWithEvents(ctx context.Context, ref reference.Named, options image.PullOptions, opts ...pull.Opt) (
 		v1.Hash, *v1.Manifest, chan events.PullEvent, error,
 	)
-	ImagePullWithState(ctx context.Context, ref reference.Named, options image.PullOptions) (
+	ImagePullWithState(ctx context.Context, ref reference.Named, options image.PullOptions, opts ...pull.Opt) (
 		v1.Hash, *v1.Manifest, chan state.Pull, error,
 	)
-	ImagePullSimple(ctx context.Context, ref reference.Named, options image.PullOptions) (digest.Digest, error)
+	ImagePullSimple(ctx context.Context, ref reference.Named, options image.PullOptions, opts ...pull.Opt) (
+		digest.Digest, error,
+	)
+	ImagePullMany(ctx context.Context, requests []pull.Request, concurrency int) (
+		chan *state.Multi, chan []pull.Result, error,
+	)
 	ImageGetManifest(ctx context.Context, ref reference.Named, platform *v1.Platform) (v1.Hash, *v1.Manifest, error)
+	ImageResolve(ctx context.Context, ref reference.Named, platform *v1.Platform) (*inspect.Resolved, error)
+	ImageInspectRemote(ctx context.Context, ref reference.Named, opts ...inspect.Opt) (*inspect.Image, error)
+	ImageReferrers(ctx context.Context, ref reference.Named) ([]inspect.Referrer, error)
+	ImageFetchReferrerBlob(ctx context.Context, referrer inspect.Referrer, blob v1.Descriptor) (io.ReadCloser, error)
 }
 
 type ContainerClient interface {
 	StreamLogs(ctx context.Context, id string, follow bool) (*stream.MultiplexedStream, error)
+	ContainerLogsStream(ctx context.Context, id string, opts ...logs.Opt) (*stream.MultiplexedStream, error)
+	ContainerAttachStream(ctx context.Context, id string, options container.AttachOptions) (*stream.Duplex, error)
+	ContainerExecAttachStream(
+		ctx context.Context, execID string, options container.ExecAttachOptions, detachKeys string,
+	) (*stream.Duplex, error)
+	ContainerExec(ctx context.Context, id string, cmd []string, opts ...exec.Opt) (*exec.Result, error)
+	ContainerExecStream(ctx context.Context, id string, cmd []string, opts ...exec.Opt) (*exec.Process, error)
+	ContainerWaitReady(ctx context.Context, id string, strategy wait.Strategy) error
+	NewSession(ctx context.Context, opts ...managed.Opt) (*managed.Session, error)
 }
 
 type SystemClient interface {
 	SystemHostIPFromContainers(ctx context.Context, netId *string) (net.IP, error)
+	SystemHostIP(ctx context.Context, netId *string) (*hostip.Result, error)
+	SystemHostIPRefresh(ctx context.Context, netId *string) (*hostip.Result, error)
 	SystemIsPodman(ctx context.Context) (bool, error)
+	SystemCapabilities(ctx context.Context) (*capabilities.Capabilities, error)
+	SystemCapabilitiesRefresh(ctx context.Context) (*capabilities.Capabilities, error)
 	SystemDefaultPlatform(ctx context.Context) (*v1.Platform, error)
+	SystemPlatforms(ctx context.Context) ([]v1.Platform, error)
 }
 
 type ClientWrapper interface {
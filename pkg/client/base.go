@@ -6,6 +6,7 @@ import (
 )
 
 func (c *Client) Close() error {
+	c.closeCancel()
 	return c.DockerClient.Close()
 }
 
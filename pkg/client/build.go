@@ -4,16 +4,185 @@ import (
 	"context"
 	"io"
 	"maps"
+	"os"
+	"path/filepath"
 
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder"
+	buildevents "github.com/silenium-dev/docker-wrapper/pkg/client/builder/events"
+	buildstate "github.com/silenium-dev/docker-wrapper/pkg/client/builder/state"
 )
 
+// ImageBuild builds an image, sending only the credentials of registries the Dockerfile references
+// (see builder.WithAllAuthConfigs and WithBuildAllAuthConfigs to send all)
 func (c *Client) ImageBuild(
 	ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions,
 ) (build.ImageBuildResponse, error) {
+	buildContext, opts, err := c.prepareBuild(ctx, buildContext, opts, c.buildDefaults)
+	if err != nil {
+		return build.ImageBuildResponse{}, err
+	}
+	return c.DockerClient.ImageBuild(ctx, buildContext, opts)
+}
+
+// prepareBuild adds the credentials of the auth provider, rewrites cache images to their mirrors and pulls the
+// images the Dockerfile references through their mirrors (see pullMirrored).
+// The returned context replaces buildContext, which may have been read to find the Dockerfile.
+func (c *Client) prepareBuild(
+	ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions, buildOpts builder.Options,
+) (io.Reader, build.ImageBuildOptions, error) {
+	cacheFrom := make([]string, 0, len(opts.CacheFrom))
+	var cacheRefs []reference.Named
+	for _, image := range opts.CacheFrom {
+		ref, err := reference.ParseNormalizedNamed(image)
+		if err != nil {
+			cacheFrom = append(cacheFrom, image)
+			continue
+		}
+		ref, err = c.rewriteRef(ref)
+		if err != nil {
+			return nil, opts, err
+		}
+		cacheFrom = append(cacheFrom, ref.String())
+		cacheRefs = append(cacheRefs, ref)
+	}
+	opts.CacheFrom = cacheFrom
+
+	// with PullParent, the engine pulls the images itself, even if they are present locally
+	pullMirrored := c.rewriter != nil && !opts.PullParent
+	scopeAuth := c.authProvider != nil && !buildOpts.AllAuthConfigs
+	var refs []reference.Named
+	if pullMirrored || scopeAuth {
+		var err error
+		buildContext, refs, err = c.buildReferences(buildContext, opts, buildOpts)
+		if err != nil {
+			return nil, opts, err
+		}
+	}
+	if pullMirrored {
+		c.pullMirrored(ctx, refs, opts.Platform)
+	}
+
+	if c.authProvider == nil {
+		return buildContext, opts, nil
+	}
 	authConfigs := c.authProvider.AuthConfigs()
+	if scopeAuth {
+		authConfigs = c.scopedAuthConfigs(append(refs, cacheRefs...))
+	}
 	maps.Copy(authConfigs, opts.AuthConfigs)
 	opts.AuthConfigs = authConfigs
+	return buildContext, opts, nil
+}
 
-	return c.DockerClient.ImageBuild(ctx, buildContext, opts)
+// buildReferences returns the images referenced by the Dockerfile of the build.
+// No images are returned if the Dockerfile can't be read, e.g. for remote contexts, so no credentials are sent and no
+// images are pulled through mirrors then.
+func (c *Client) buildReferences(
+	buildContext io.Reader, opts build.ImageBuildOptions, buildOpts builder.Options,
+) (io.Reader, []reference.Named, error) {
+	name := opts.Dockerfile
+	if name == "" {
+		name = builder.DefaultDockerfile
+	}
+
+	var dockerfile []byte
+	var err error
+	switch {
+	case buildContext == nil && buildOpts.LocalDirs[builder.DockerfileSessionDir] != "":
+		dockerfile, err = os.ReadFile(filepath.Join(buildOpts.LocalDirs[builder.DockerfileSessionDir], name))
+	case buildContext == nil:
+	default:
+		if tarContext, ok := buildContext.(*builder.Context); ok {
+			dockerfile, err = tarContext.ReadDockerfile()
+		} else {
+			dockerfile, buildContext, err = builder.PeekDockerfile(buildContext, name)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if dockerfile == nil {
+		c.logger.Warnf(
+			"failed to find Dockerfile %s in build context, not sending registry credentials "+
+				"(use builder.WithAllAuthConfigs to send all) and not pulling its images through mirrors", name,
+		)
+		return buildContext, nil, nil
+	}
+
+	refs, err := builder.ReferencedImages(dockerfile, opts.BuildArgs)
+	if err != nil {
+		// the engine reports the invalid Dockerfile
+		c.logger.Debugf("not sending registry credentials: %v", err)
+		return buildContext, nil, nil
+	}
+	return buildContext, refs, nil
+}
+
+// pullMirrored pulls the images of refs which have a mirror through it. The engine resolves the images of a Dockerfile
+// itself, without the client's mirrors, but uses local images if they are present. Pulled images are tagged with
+// their original reference, so the engine finds them. Digest references can't be tagged, they are left to the engine,
+// as are images whose pull failed.
+func (c *Client) pullMirrored(ctx context.Context, refs []reference.Named, platform string) {
+	for _, ref := range refs {
+		if _, canonical := ref.(reference.Canonical); canonical {
+			continue
+		}
+		ref = reference.TagNameOnly(ref)
+		mirrored, err := c.rewriteRef(ref)
+		if err != nil || mirrored.String() == ref.String() {
+			continue
+		}
+		if _, err := c.ImagePullSimple(ctx, ref, image.PullOptions{Platform: platform}); err != nil {
+			c.logger.Warnf("failed to pull %s through mirror %s, leaving it to the engine: %v", ref.String(), mirrored.String(), err)
+		}
+	}
+}
+
+// dockerHubAuthKey is the key the engine looks up Docker Hub credentials with
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// scopedAuthConfigs returns the credentials for refs, keyed like the engine looks them up
+func (c *Client) scopedAuthConfigs(refs []reference.Named) map[string]registry.AuthConfig {
+	result := map[string]registry.AuthConfig{}
+	for _, ref := range refs {
+		authConfig := c.authProvider.AuthConfig(ref)
+		if authConfig == (registry.AuthConfig{}) {
+			continue
+		}
+		key := reference.Domain(ref)
+		if key == "docker.io" {
+			key = dockerHubAuthKey
+		}
+		result[key] = authConfig
+	}
+	return result
+}
+
+// ImageBuildWithEvents builds an image and returns the typed build event stream.
+// The stream ends with an *events.BuildError if the build failed.
+// Secrets, SSH agents and local directories (see builder.Opt) are served through a BuildKit session on docker,
+// podman supports secrets and the context directory only.
+func (c *Client) ImageBuildWithEvents(
+	ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions, buildOpts ...builder.Opt,
+) (chan buildevents.BuildEvent, error) {
+	body, err := c.imageBuild(ctx, buildContext, opts, builder.RenderOptions(c.buildDefaults, buildOpts))
+	if err != nil {
+		return nil, err
+	}
+	return builder.ParseStream(ctx, body), nil
+}
+
+// ImageBuildWithState builds an image and returns the build state after each event
+func (c *Client) ImageBuildWithState(
+	ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions, buildOpts ...builder.Opt,
+) (chan buildstate.Build, error) {
+	eventChan, err := c.ImageBuildWithEvents(ctx, buildContext, opts, buildOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return builder.StateFromStream(ctx, eventChan, c.logger), nil
 }
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/moby/buildkit/session"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder"
+	podmanclient "github.com/silenium-dev/docker-wrapper/pkg/client/podman/client"
+	"github.com/silenium-dev/docker-wrapper/pkg/errors"
+)
+
+// imageBuild starts the build and returns the message stream, attaching a BuildKit session if needed
+func (c *Client) imageBuild(
+	ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions, buildOpts builder.Options,
+) (io.ReadCloser, error) {
+	if !buildOpts.NeedsSession() {
+		buildContext, opts, err := c.prepareBuild(ctx, buildContext, opts, buildOpts)
+		if err != nil {
+			return nil, err
+		}
+		response, err := c.DockerClient.ImageBuild(ctx, buildContext, opts)
+		if err != nil {
+			return nil, err
+		}
+		return response.Body, nil
+	}
+
+	caps, err := c.SystemCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if caps.IsPodman() {
+		return c.imageBuildPodman(ctx, buildContext, opts, buildOpts)
+	}
+	if !caps.BuildKit {
+		return nil, &errors.UnsupportedError{Feature: "build sessions without BuildKit", Engine: "docker"}
+	}
+	return c.imageBuildSession(ctx, buildContext, opts, buildOpts)
+}
+
+func (c *Client) imageBuildSession(
+	ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions, buildOpts builder.Options,
+) (io.ReadCloser, error) {
+	sess, err := builder.NewSession(ctx, buildOpts)
+	if err != nil {
+		return nil, err
+	}
+	dialer := func(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) {
+		return c.DialHijack(ctx, "/session", proto, meta)
+	}
+	go func() {
+		if err := sess.Run(ctx, dialer); err != nil {
+			c.logger.Warnf("build session %s failed: %v", sess.ID(), err)
+		}
+	}()
+
+	opts.SessionID = sess.ID()
+	opts.Version = build.BuilderBuildKit
+	if _, ok := buildOpts.LocalDirs[builder.ContextSessionDir]; ok && buildContext == nil {
+		opts.RemoteContext = builder.ClientSessionContext
+	}
+	buildContext, opts, err = c.prepareBuild(ctx, buildContext, opts, buildOpts)
+	if err != nil {
+		_ = sess.Close()
+		return nil, err
+	}
+	response, err := c.DockerClient.ImageBuild(ctx, buildContext, opts)
+	if err != nil {
+		_ = sess.Close()
+		return nil, err
+	}
+	return &sessionBody{ReadCloser: response.Body, session: sess}, nil
+}
+
+// imageBuildPodman emulates the session features podman supports: secrets are passed as files in the context and
+// the context directory is uploaded as tar
+func (c *Client) imageBuildPodman(
+	ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions, buildOpts builder.Options,
+) (io.ReadCloser, error) {
+	unsupported := func(feature string) error {
+		return &errors.UnsupportedError{Feature: feature, Engine: "podman"}
+	}
+	if len(buildOpts.SSH) > 0 {
+		return nil, unsupported("ssh forwarding")
+	}
+	if buildOpts.OutputDir != "" {
+		return nil, unsupported("local build outputs")
+	}
+	for name := range buildOpts.LocalDirs {
+		if name != builder.ContextSessionDir && name != builder.DockerfileSessionDir {
+			return nil, unsupported("local directory " + name)
+		}
+	}
+
+	if contextDir, ok := buildOpts.LocalDirs[builder.ContextSessionDir]; ok && buildContext == nil {
+		dockerfile := opts.Dockerfile
+		if dockerfile == "" {
+			dockerfile = builder.DefaultDockerfile
+		}
+		if dockerfileDir, ok := buildOpts.LocalDirs[builder.DockerfileSessionDir]; ok {
+			dockerfile = filepath.Join(dockerfileDir, dockerfile)
+		}
+		tarContext, err := builder.NewContext(contextDir, builder.WithDockerfile(dockerfile))
+		if err != nil {
+			return nil, err
+		}
+		opts.Dockerfile = tarContext.Dockerfile
+		buildContext = tarContext
+	}
+
+	buildContext, opts, err := c.prepareBuild(ctx, buildContext, opts, buildOpts)
+	if err != nil {
+		return nil, err
+	}
+	podman, err := podmanclient.FromDocker(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []string
+	if len(buildOpts.Secrets) > 0 {
+		// the http client closes the request body once it is sent
+		buildContext, secrets, err = builder.WithSecretFiles(buildContext, buildOpts.Secrets)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return podman.ImageBuild(ctx, buildContext, opts, secrets)
+}
+
+// sessionBody closes the build session together with the message stream
+type sessionBody struct {
+	io.ReadCloser
+	session *session.Session
+}
+
+func (b *sessionBody) Close() error {
+	err := b.ReadCloser.Close()
+	_ = b.session.Close()
+	return err
+}
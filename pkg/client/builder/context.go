@@ -0,0 +1,281 @@
+package builder
+
+import (
+	"archive/tar"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/go-archive"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+const (
+	DefaultDockerfile = "Dockerfile"
+	dockerignoreName  = ".dockerignore"
+	tarBlockSize      = 512
+)
+
+type ContextOptions struct {
+	// Dockerfile is relative to the context directory or absolute, it may be outside the context
+	Dockerfile string
+	// ExcludePatterns are applied in addition to the .dockerignore patterns
+	ExcludePatterns []string
+	Progress        func(ContextProgress)
+}
+
+type ContextOpt func(*ContextOptions)
+
+func WithDockerfile(path string) ContextOpt {
+	return func(o *ContextOptions) {
+		o.Dockerfile = path
+	}
+}
+
+func WithExcludes(patterns ...string) ContextOpt {
+	return func(o *ContextOptions) {
+		o.ExcludePatterns = append(o.ExcludePatterns, patterns...)
+	}
+}
+
+// WithContextProgress registers a callback which is called from Read as the context is consumed
+func WithContextProgress(progress func(ContextProgress)) ContextOpt {
+	return func(o *ContextOptions) {
+		o.Progress = progress
+	}
+}
+
+// ContextProgress is the upload progress of a build context
+type ContextProgress struct {
+	Sent int64
+	// Total is the estimated size of the tar stream, long file names and extended attributes are not accounted for
+	Total int64
+	Done  bool
+}
+
+func (p ContextProgress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return min(float64(p.Sent)/float64(p.Total), 1)
+}
+
+// Context is a build context tar, which is streamed from its directory while it is read
+type Context struct {
+	reader io.ReadCloser
+	// Dockerfile is the path of the Dockerfile inside the context, to be passed as ImageBuildOptions.Dockerfile
+	Dockerfile string
+	// Files is the number of files and directories in the context
+	Files int
+	// Size is the total size of the files in the context
+	Size int64
+	// Excludes are the effective exclude patterns
+	Excludes []string
+
+	dockerfilePath string
+	progress       func(ContextProgress)
+	total          int64
+	sent           int64
+	closeOnce      sync.Once
+}
+
+// NewContext creates the build context of dir, honouring <Dockerfile>.dockerignore or, if it doesn't exist,
+// .dockerignore. A Dockerfile outside of dir is added to the context under a random name.
+func NewContext(dir string, opts ...ContextOpt) (*Context, error) {
+	options := ContextOptions{Dockerfile: DefaultDockerfile}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to stat context directory: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("context %s is not a directory", dir)
+	}
+
+	dockerfile := options.Dockerfile
+	if !filepath.IsAbs(dockerfile) {
+		dockerfile = filepath.Join(dir, dockerfile)
+	}
+	if _, err := os.Stat(dockerfile); err != nil {
+		return nil, fmt.Errorf("failed to stat Dockerfile: %w", err)
+	}
+	relDockerfile, err := filepath.Rel(dir, dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	external := relDockerfile == ".." || strings.HasPrefix(relDockerfile, ".."+string(filepath.Separator))
+
+	excludes, err := readIgnoreFile(dir, dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	excludes = append(excludes, options.ExcludePatterns...)
+
+	result := &Context{progress: options.Progress, Excludes: excludes, dockerfilePath: dockerfile}
+	if external {
+		result.Dockerfile = ".dockerfile." + randomSuffix()
+	} else {
+		result.Dockerfile = filepath.ToSlash(relDockerfile)
+		// the engine needs the Dockerfile and .dockerignore even if they are excluded
+		result.Excludes = keepFile(result.Excludes, result.Dockerfile)
+		result.Excludes = keepFile(result.Excludes, dockerignoreName)
+	}
+
+	if err := result.measure(dir); err != nil {
+		return nil, err
+	}
+
+	reader, err := archive.TarWithOptions(dir, &archive.TarOptions{
+		ExcludePatterns: result.Excludes,
+		ChownOpts:       &archive.ChownOpts{UID: 0, GID: 0},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create context archive: %w", err)
+	}
+	if external {
+		content, err := os.ReadFile(dockerfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Dockerfile: %w", err)
+		}
+		reader = addDockerfile(reader, content, result.Dockerfile)
+		result.total += tarBlockSize + int64(len(content)+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	}
+	result.reader = reader
+	return result, nil
+}
+
+func (c *Context) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.sent += int64(n)
+	if c.progress != nil && (n > 0 || err == io.EOF) {
+		c.progress(ContextProgress{Sent: c.sent, Total: max(c.total, c.sent), Done: err == io.EOF})
+	}
+	return n, err
+}
+
+func (c *Context) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.reader.Close()
+	})
+	return err
+}
+
+// ReadDockerfile reads the Dockerfile from disk
+func (c *Context) ReadDockerfile() ([]byte, error) {
+	return os.ReadFile(c.dockerfilePath)
+}
+
+// EstimatedSize is the estimated size of the tar stream
+func (c *Context) EstimatedSize() int64 {
+	return c.total
+}
+
+// measure walks the context like the archiver does to compute sizes before streaming
+func (c *Context) measure(dir string) error {
+	matcher, err := patternmatcher.New(c.Excludes)
+	if err != nil {
+		return fmt.Errorf("invalid exclude patterns: %w", err)
+	}
+	parentInfo := map[string]patternmatcher.MatchInfo{}
+	c.total = 2 * tarBlockSize
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		skip, info, err := matcher.MatchesUsingParentResults(rel, parentInfo[filepath.Dir(rel)])
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			parentInfo[rel] = info
+		}
+		if skip {
+			if entry.IsDir() && !matcher.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		c.Files++
+		c.total += tarBlockSize
+		if entry.Type().IsRegular() {
+			fileInfo, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			c.Size += fileInfo.Size()
+			c.total += (fileInfo.Size() + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+		}
+		return nil
+	})
+}
+
+// readIgnoreFile reads the Dockerfile specific ignore file next to the Dockerfile, or the context's .dockerignore
+func readIgnoreFile(dir, dockerfile string) ([]string, error) {
+	for _, path := range []string{dockerfile + dockerignoreName, filepath.Join(dir, dockerignoreName)} {
+		file, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		patterns, err := ignorefile.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return patterns, nil
+	}
+	return nil, nil
+}
+
+func keepFile(excludes []string, name string) []string {
+	if excluded, _ := patternmatcher.MatchesOrParentMatches(name, excludes); excluded {
+		return append(excludes, "!"+name)
+	}
+	return excludes
+}
+
+// addDockerfile appends the Dockerfile to the context and excludes it (and .dockerignore) from COPY.
+// The ignore patterns were already applied to the archive, so the .dockerignore sent to the engine only lists these
+// two files. Patterns like * would otherwise match the Dockerfile's random name, and the patterns of a Dockerfile
+// specific ignore file would differ from the context's.
+func addDockerfile(reader io.ReadCloser, content []byte, name string) io.ReadCloser {
+	modTime := time.Now()
+	return archive.ReplaceFileTarWrapper(reader, map[string]archive.TarModifierFunc{
+		name: func(_ string, _ *tar.Header, _ io.Reader) (*tar.Header, []byte, error) {
+			return &tar.Header{
+				Name: name, Mode: 0o600, ModTime: modTime, Typeflag: tar.TypeReg,
+			}, content, nil
+		},
+		dockerignoreName: func(_ string, header *tar.Header, _ io.Reader) (*tar.Header, []byte, error) {
+			if header == nil {
+				header = &tar.Header{Mode: 0o600, ModTime: modTime, Typeflag: tar.TypeReg}
+			}
+			return header, []byte(dockerignoreName + "\n" + name + "\n"), nil
+		},
+	})
+}
+
+func randomSuffix() string {
+	data := make([]byte, 8)
+	_, _ = rand.Read(data)
+	return hex.EncodeToString(data)
+}
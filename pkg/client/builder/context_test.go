@@ -0,0 +1,124 @@
+package builder
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moby/patternmatcher/ignorefile"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func readContext(t *testing.T, context *Context) map[string]string {
+	defer func() { require.NoError(t, context.Close()) }()
+	result := map[string]string{}
+	reader := tar.NewReader(context)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			// the tar reader stops at the end marker, the engine reads the stream to its end
+			_, err = io.Copy(io.Discard, context)
+			require.NoError(t, err)
+			return result
+		}
+		require.NoError(t, err)
+		if header.Typeflag == tar.TypeReg {
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			result[header.Name] = string(data)
+		}
+	}
+}
+
+func TestContextDockerignore(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"Dockerfile":        "FROM alpine\n",
+		".dockerignore":     "**/*.log\nDockerfile\n",
+		"app/main.go":       "package main\n",
+		"app/debug.log":     "debug",
+		"build.log":         "build",
+		"node_modules/x.js": "x",
+	})
+
+	var last ContextProgress
+	context, err := NewContext(dir, WithExcludes("node_modules"), WithContextProgress(func(p ContextProgress) {
+		last = p
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "Dockerfile", context.Dockerfile)
+
+	files := readContext(t, context)
+	require.Contains(t, files, "Dockerfile")
+	require.Contains(t, files, ".dockerignore")
+	require.Contains(t, files, "app/main.go")
+	require.NotContains(t, files, "app/debug.log")
+	require.NotContains(t, files, "build.log")
+	require.NotContains(t, files, "node_modules/x.js")
+
+	require.True(t, last.Done)
+	require.Equal(t, context.EstimatedSize(), last.Sent)
+	require.Equal(t, int64(len("FROM alpine\n")+len("**/*.log\nDockerfile\n")+len("package main\n")), context.Size)
+}
+
+func TestContextExternalDockerfile(t *testing.T) {
+	dir := t.TempDir()
+	external := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".dockerignore": "*.log\n",
+		"build.log":     "build",
+		"secret.txt":    "secret",
+		"main.go":       "package main\n",
+	})
+	writeFiles(t, external, map[string]string{
+		"app.Dockerfile":              "FROM scratch\n",
+		"app.Dockerfile.dockerignore": "secret.txt\n",
+	})
+
+	context, err := NewContext(dir, WithDockerfile(filepath.Join(external, "app.Dockerfile")))
+	require.NoError(t, err)
+
+	files := readContext(t, context)
+	require.Equal(t, "FROM scratch\n", files[context.Dockerfile])
+	require.Contains(t, files, "main.go")
+	// the Dockerfile specific ignore file replaces .dockerignore
+	require.Contains(t, files, "build.log")
+	require.NotContains(t, files, "secret.txt")
+	// the engine must not apply the context's patterns to what the Dockerfile specific ignore file kept
+	require.Equal(t, ".dockerignore\n"+context.Dockerfile+"\n", files[".dockerignore"])
+}
+
+func TestContextExternalDockerfileIgnoreAll(t *testing.T) {
+	dir := t.TempDir()
+	external := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".dockerignore": "*\n!main.go\n",
+		"main.go":       "package main\n",
+		"secret.txt":    "secret",
+	})
+	writeFiles(t, external, map[string]string{"app.Dockerfile": "FROM scratch\n"})
+
+	context, err := NewContext(dir, WithDockerfile(filepath.Join(external, "app.Dockerfile")))
+	require.NoError(t, err)
+
+	files := readContext(t, context)
+	require.Equal(t, "FROM scratch\n", files[context.Dockerfile])
+	require.Contains(t, files, "main.go")
+	require.NotContains(t, files, "secret.txt")
+	// * would match the Dockerfile's random name, only the explicit entries remain
+	excludes, err := ignorefile.ReadAll(strings.NewReader(files[".dockerignore"]))
+	require.NoError(t, err)
+	require.Equal(t, []string{dockerignoreName, context.Dockerfile}, excludes)
+}
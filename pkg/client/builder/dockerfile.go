@@ -0,0 +1,93 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/moby/buildkit/frontend/dockerfile/shell"
+)
+
+// ReferencedImages returns the images a Dockerfile pulls: the bases of its stages, COPY --from and
+// RUN --mount from images. Global ARGs are substituted, with buildArgs taking precedence over their defaults.
+// References to stages and scratch are skipped, as are references which are still invalid after substitution.
+func ReferencedImages(dockerfile []byte, buildArgs map[string]*string) ([]reference.Named, error) {
+	ast, err := parser.Parse(bytes.NewReader(dockerfile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile: %w", err)
+	}
+	stages, metaArgs, err := instructions.Parse(ast.AST, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile: %w", err)
+	}
+
+	lex := shell.NewLex(ast.EscapeToken)
+	env := expandMetaArgs(lex, metaArgs, buildArgs)
+
+	var result []reference.Named
+	seen := map[string]bool{}
+	stageNames := map[string]bool{}
+	add := func(image string) {
+		expanded, _, err := lex.ProcessWord(image, shell.EnvsFromSlice(env))
+		if err != nil || expanded == "" || stageNames[strings.ToLower(expanded)] {
+			return
+		}
+		if _, err := strconv.Atoi(expanded); err == nil || expanded == "scratch" {
+			return
+		}
+		ref, err := reference.ParseNormalizedNamed(expanded)
+		if err != nil || seen[ref.String()] {
+			return
+		}
+		seen[ref.String()] = true
+		result = append(result, ref)
+	}
+
+	for _, stage := range stages {
+		add(stage.BaseName)
+		for _, command := range stage.Commands {
+			switch command := command.(type) {
+			case *instructions.CopyCommand:
+				if command.From != "" {
+					add(command.From)
+				}
+			case *instructions.RunCommand:
+				for _, mount := range instructions.GetMounts(command) {
+					if mount.From != "" {
+						add(mount.From)
+					}
+				}
+			}
+		}
+		if stage.Name != "" {
+			stageNames[strings.ToLower(stage.Name)] = true
+		}
+	}
+	return result, nil
+}
+
+// expandMetaArgs evaluates the ARGs before the first FROM in order, each may refer to the previous ones
+func expandMetaArgs(lex *shell.Lex, metaArgs []instructions.ArgCommand, buildArgs map[string]*string) []string {
+	var env []string
+	for _, arg := range metaArgs {
+		for _, kv := range arg.Args {
+			value := kv.Value
+			if override, ok := buildArgs[kv.Key]; ok && override != nil {
+				value = override
+			} else if value != nil {
+				expanded, _, err := lex.ProcessWord(*value, shell.EnvsFromSlice(env))
+				if err == nil {
+					value = &expanded
+				}
+			}
+			if value != nil {
+				env = append(env, kv.Key+"="+*value)
+			}
+		}
+	}
+	return env
+}
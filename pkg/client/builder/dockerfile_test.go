@@ -0,0 +1,98 @@
+package builder
+
+import (
+	"archive/tar"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferencedImages(t *testing.T) {
+	dockerfile := []byte(`
+ARG REGISTRY=registry.example.com
+ARG GO_VERSION=1.24
+ARG BASE=${REGISTRY}/base:latest
+
+FROM golang:${GO_VERSION} AS build
+COPY --from=ghcr.io/example/tools:v1 /bin/tool /bin/tool
+RUN --mount=type=bind,from=quay.io/example/data,target=/data make
+
+FROM build AS test
+RUN go test ./...
+
+FROM $BASE
+COPY --from=build /out /out
+COPY --from=0 /etc/passwd /etc/passwd
+
+FROM scratch
+COPY --from=test /out /out
+`)
+	version := "1.23"
+	refs, err := ReferencedImages(dockerfile, map[string]*string{"GO_VERSION": &version})
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, reference.FamiliarString(ref))
+	}
+	require.Equal(t, []string{
+		"golang:1.23",
+		"ghcr.io/example/tools:v1",
+		"quay.io/example/data",
+		"registry.example.com/base:latest",
+	}, names)
+}
+
+func TestPeekDockerfile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a/large.bin":           strings.Repeat("x", 10000),
+		"docker/app.Dockerfile": "FROM alpine\n",
+	})
+	context, err := NewContext(dir, WithDockerfile("docker/app.Dockerfile"))
+	require.NoError(t, err)
+
+	content, reader, err := PeekDockerfile(context, "./docker/app.Dockerfile")
+	require.NoError(t, err)
+	require.Equal(t, "FROM alpine\n", string(content))
+
+	// the returned reader still yields the whole context
+	files := readContext(t, &Context{reader: io.NopCloser(reader)})
+	require.Len(t, files["a/large.bin"], 10000)
+	require.Contains(t, files, "docker/app.Dockerfile")
+}
+
+func TestPeekDockerfileLargeEntry(t *testing.T) {
+	const largeSize = maxPeekSize + 16<<20
+	contextReader, contextWriter := io.Pipe()
+	go func() {
+		writer := tar.NewWriter(contextWriter)
+		require.NoError(t, writer.WriteHeader(&tar.Header{Name: "large.bin", Mode: 0o644, Size: largeSize}))
+		_, err := io.CopyN(writer, zeroReader{}, largeSize)
+		require.NoError(t, err)
+		dockerfile := "FROM alpine\n"
+		require.NoError(t, writer.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0o644, Size: int64(len(dockerfile))}))
+		_, err = writer.Write([]byte(dockerfile))
+		require.NoError(t, err)
+		contextWriter.CloseWithError(writer.Close())
+	}()
+
+	content, reader, err := PeekDockerfile(contextReader, "Dockerfile")
+	require.NoError(t, err)
+	require.Nil(t, content, "the Dockerfile is beyond the peek limit")
+
+	// the buffered part and the rest still form the whole context
+	files := readContext(t, &Context{reader: io.NopCloser(reader)})
+	require.Len(t, files["large.bin"], largeSize)
+	require.Equal(t, "FROM alpine\n", files["Dockerfile"])
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	clear(b)
+	return len(b), nil
+}
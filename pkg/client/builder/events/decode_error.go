@@ -0,0 +1,17 @@
+package events
+
+import "fmt"
+
+// DecodeError is emitted for lines of the build stream that could not be decoded.
+// The stream continues after a DecodeError, unless the underlying reader failed.
+type DecodeError struct {
+	Line []byte
+	Err  error
+}
+
+func (d *DecodeError) String() string {
+	if len(d.Line) == 0 {
+		return fmt.Sprintf("failed to read build stream: %v", d.Err)
+	}
+	return fmt.Sprintf("failed to decode build event %q: %v", string(d.Line), d.Err)
+}
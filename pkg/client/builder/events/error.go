@@ -0,0 +1,11 @@
+package events
+
+// BuildError is the error the build failed with, it is the last message of a failed build
+type BuildError struct {
+	Message string
+	Code    int
+}
+
+func (e *BuildError) String() string {
+	return e.Message
+}
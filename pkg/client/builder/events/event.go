@@ -0,0 +1,101 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+type BuildEvent interface {
+	String() string
+}
+
+const (
+	// TraceID marks aux messages carrying BuildKit progress (a base64 encoded StatusResponse)
+	TraceID = "moby.buildkit.trace"
+	// ImageIDID marks the aux message carrying the id of the built image (BuildKit only, the classic builder omits it)
+	ImageIDID = "moby.image.id"
+)
+
+var (
+	// stepPattern matches the step headers of the classic builder ("Step 1/3 : ") and podman ("STEP 1/3: ")
+	stepPattern = regexp.MustCompile(`^(?:Step|STEP) (\d+)/(\d+) ?: ?(.*)$`)
+	// usingCachePattern matches " ---> Using cache" (docker) and "--> Using cache <id>" (podman)
+	usingCachePattern = regexp.MustCompile(`^ ?-{2,3}> Using cache(?: ([0-9a-f]+))?$`)
+	runningInPattern  = regexp.MustCompile(`^ ?-{2,3}> Running in ([0-9a-f]+)$`)
+	committedPattern  = regexp.MustCompile(`^ ?-{2,3}> ([0-9a-f]{12,64})$`)
+	builtPattern      = regexp.MustCompile(`^Successfully built ([0-9a-f]+)$`)
+	taggedPattern     = regexp.MustCompile(`^Successfully tagged (.+)$`)
+)
+
+// Parse converts one message of the build stream into events.
+// BuildKit trace messages contain several vertexes, statuses and logs, which are returned in that order.
+func Parse(message jsonmessage.JSONMessage) ([]BuildEvent, error) {
+	switch {
+	case message.Error != nil:
+		return []BuildEvent{&BuildError{Message: message.Error.Message, Code: message.Error.Code}}, nil
+	case message.ErrorMessage != "":
+		return []BuildEvent{&BuildError{Message: message.ErrorMessage}}, nil
+	case message.Aux != nil:
+		return parseAux(message)
+	case message.Stream != "":
+		return []BuildEvent{parseStream(message.Stream)}, nil
+	case message.Status != "":
+		progress := &Progress{ID: message.ID, Status: message.Status}
+		if message.Progress != nil {
+			progress.Current = message.Progress.Current
+			progress.Total = message.Progress.Total
+		}
+		return []BuildEvent{progress}, nil
+	}
+	return []BuildEvent{&UnknownEvent{message}}, nil
+}
+
+func parseAux(message jsonmessage.JSONMessage) ([]BuildEvent, error) {
+	if message.ID == TraceID {
+		var data []byte
+		if err := json.Unmarshal(*message.Aux, &data); err != nil {
+			return nil, fmt.Errorf("invalid trace message: %w", err)
+		}
+		return parseTrace(data)
+	}
+	var aux struct {
+		ID string `json:"ID"`
+	}
+	if err := json.Unmarshal(*message.Aux, &aux); err != nil || aux.ID == "" {
+		return []BuildEvent{&UnknownEvent{message}}, nil
+	}
+	return []BuildEvent{&ImageID{ID: aux.ID}}, nil
+}
+
+func parseStream(stream string) BuildEvent {
+	line := strings.TrimRight(stream, "\n")
+	if strings.Contains(line, "\n") {
+		return &Stream{Text: stream}
+	}
+	if match := stepPattern.FindStringSubmatch(line); match != nil {
+		number, _ := strconv.Atoi(match[1])
+		total, _ := strconv.Atoi(match[2])
+		return &StepStarted{Number: number, Total: total, Instruction: match[3]}
+	}
+	if match := usingCachePattern.FindStringSubmatch(line); match != nil {
+		return &StepCached{ImageID: match[1]}
+	}
+	if match := runningInPattern.FindStringSubmatch(line); match != nil {
+		return &StepRunning{ContainerID: match[1]}
+	}
+	if match := committedPattern.FindStringSubmatch(line); match != nil {
+		return &StepCommitted{ImageID: match[1]}
+	}
+	if match := builtPattern.FindStringSubmatch(line); match != nil {
+		return &Built{ImageID: match[1]}
+	}
+	if match := taggedPattern.FindStringSubmatch(line); match != nil {
+		return &Tagged{Tag: match[1]}
+	}
+	return &Stream{Text: stream}
+}
@@ -0,0 +1,26 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/docker/go-units"
+)
+
+// Progress is a status message, e.g. of base images being pulled by the classic builder
+type Progress struct {
+	ID      string
+	Status  string
+	Current int64
+	Total   int64
+}
+
+func (p *Progress) String() string {
+	status := p.Status
+	if p.Total > 0 {
+		status = fmt.Sprintf("%s %s/%s", status, units.HumanSize(float64(p.Current)), units.HumanSize(float64(p.Total)))
+	}
+	if p.ID != "" {
+		return fmt.Sprintf("[%s] %s", p.ID, status)
+	}
+	return status
+}
@@ -0,0 +1,29 @@
+package events
+
+import "fmt"
+
+// ImageID is the aux message with the full id of the built image
+type ImageID struct {
+	ID string
+}
+
+func (i *ImageID) String() string {
+	return fmt.Sprintf("Image ID: %s", i.ID)
+}
+
+// Built is the classic builder's "Successfully built" message with the short image id
+type Built struct {
+	ImageID string
+}
+
+func (b *Built) String() string {
+	return fmt.Sprintf("Successfully built %s", b.ImageID)
+}
+
+type Tagged struct {
+	Tag string
+}
+
+func (t *Tagged) String() string {
+	return fmt.Sprintf("Successfully tagged %s", t.Tag)
+}
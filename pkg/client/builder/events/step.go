@@ -0,0 +1,45 @@
+package events
+
+import "fmt"
+
+// StepStarted is emitted by the classic builder and podman when a Dockerfile instruction starts
+type StepStarted struct {
+	Number      int
+	Total       int
+	Instruction string
+}
+
+func (s *StepStarted) String() string {
+	return fmt.Sprintf("Step %d/%d : %s", s.Number, s.Total, s.Instruction)
+}
+
+// StepCached is emitted when the current step is taken from the build cache.
+// Podman includes the id of the cached image, docker reports it with the following StepCommitted.
+type StepCached struct {
+	ImageID string
+}
+
+func (s *StepCached) String() string {
+	if s.ImageID != "" {
+		return fmt.Sprintf("Using cache %s", s.ImageID)
+	}
+	return "Using cache"
+}
+
+// StepRunning is emitted when the classic builder starts the container of the current step
+type StepRunning struct {
+	ContainerID string
+}
+
+func (s *StepRunning) String() string {
+	return fmt.Sprintf("Running in %s", s.ContainerID)
+}
+
+// StepCommitted is emitted when the current step has produced its (short) image id
+type StepCommitted struct {
+	ImageID string
+}
+
+func (s *StepCommitted) String() string {
+	return fmt.Sprintf("---> %s", s.ImageID)
+}
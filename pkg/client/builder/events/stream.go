@@ -0,0 +1,12 @@
+package events
+
+import "strings"
+
+// Stream is build output which is not a recognised builder message, usually the output of RUN instructions
+type Stream struct {
+	Text string
+}
+
+func (s *Stream) String() string {
+	return strings.TrimRight(s.Text, "\n")
+}
@@ -0,0 +1,140 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Vertex is a BuildKit build step, it is sent again whenever it starts, completes or fails
+type Vertex struct {
+	Digest string
+	Inputs []string
+	Name   string
+	Cached bool
+	// Started and Completed are nil until the vertex started or completed
+	Started   *time.Time
+	Completed *time.Time
+	Error     string
+}
+
+func (v *Vertex) String() string {
+	switch {
+	case v.Error != "":
+		return fmt.Sprintf("%s: ERROR: %s", v.Name, v.Error)
+	case v.Cached:
+		return fmt.Sprintf("%s: CACHED", v.Name)
+	case v.Completed != nil:
+		return fmt.Sprintf("%s: DONE", v.Name)
+	}
+	return v.Name
+}
+
+// VertexStatus is the progress of one operation (e.g. a layer download) of a vertex
+type VertexStatus struct {
+	ID        string
+	Vertex    string
+	Name      string
+	Current   int64
+	Total     int64
+	Timestamp time.Time
+	Started   *time.Time
+	Completed *time.Time
+}
+
+func (s *VertexStatus) String() string {
+	if s.Total > 0 {
+		return fmt.Sprintf("%s %d/%d", s.ID, s.Current, s.Total)
+	}
+	return fmt.Sprintf("%s %d", s.ID, s.Current)
+}
+
+// VertexLog is output of a vertex, Stream is 1 for stdout and 2 for stderr
+type VertexLog struct {
+	Vertex    string
+	Stream    int
+	Data      []byte
+	Timestamp time.Time
+}
+
+func (l *VertexLog) String() string {
+	return strings.TrimRight(string(l.Data), "\n")
+}
+
+// VertexWarning is a build check or deprecation warning of a vertex
+type VertexWarning struct {
+	Vertex string
+	Level  int
+	Short  string
+	Detail []string
+	URL    string
+}
+
+func (w *VertexWarning) String() string {
+	return fmt.Sprintf("WARNING: %s", w.Short)
+}
+
+func parseTrace(data []byte) ([]BuildEvent, error) {
+	var status controlapi.StatusResponse
+	if err := status.UnmarshalVT(data); err != nil {
+		return nil, fmt.Errorf("invalid trace message: %w", err)
+	}
+
+	result := make([]BuildEvent, 0, len(status.Vertexes)+len(status.Statuses)+len(status.Logs)+len(status.Warnings))
+	for _, v := range status.Vertexes {
+		result = append(result, &Vertex{
+			Digest:    v.Digest,
+			Inputs:    v.Inputs,
+			Name:      v.Name,
+			Cached:    v.Cached,
+			Started:   timePtr(v.Started),
+			Completed: timePtr(v.Completed),
+			Error:     v.Error,
+		})
+	}
+	for _, s := range status.Statuses {
+		result = append(result, &VertexStatus{
+			ID:        s.ID,
+			Vertex:    s.Vertex,
+			Name:      s.Name,
+			Current:   s.Current,
+			Total:     s.Total,
+			Timestamp: s.Timestamp.AsTime(),
+			Started:   timePtr(s.Started),
+			Completed: timePtr(s.Completed),
+		})
+	}
+	for _, l := range status.Logs {
+		result = append(result, &VertexLog{
+			Vertex:    l.Vertex,
+			Stream:    int(l.Stream),
+			Data:      l.Msg,
+			Timestamp: l.Timestamp.AsTime(),
+		})
+	}
+	for _, w := range status.Warnings {
+		detail := make([]string, 0, len(w.Detail))
+		for _, d := range w.Detail {
+			detail = append(detail, string(d))
+		}
+		result = append(result, &VertexWarning{
+			Vertex: w.Vertex,
+			Level:  int(w.Level),
+			Short:  string(w.Short),
+			Detail: detail,
+			URL:    w.Url,
+		})
+	}
+	return result, nil
+}
+
+func timePtr(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
@@ -0,0 +1,19 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// UnknownEvent is emitted for messages Parse does not recognise, so that new daemon messages don't break consumers
+type UnknownEvent struct {
+	Raw jsonmessage.JSONMessage
+}
+
+func (u *UnknownEvent) String() string {
+	if u.Raw.ID != "" {
+		return fmt.Sprintf("[%s] unknown build message", u.Raw.ID)
+	}
+	return "unknown build message"
+}
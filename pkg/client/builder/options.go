@@ -0,0 +1,99 @@
+package builder
+
+import (
+	"maps"
+	"slices"
+)
+
+// ContextSessionDir and DockerfileSessionDir are the local directories BuildKit reads the context and the
+// Dockerfile from when ImageBuildOptions.RemoteContext is "client-session"
+const (
+	ContextSessionDir    = "context"
+	DockerfileSessionDir = "dockerfile"
+	ClientSessionContext = "client-session"
+)
+
+type Options struct {
+	Secrets []Secret
+	SSH     []SSHAgent
+	// LocalDirs are synced to the builder on demand, by name
+	LocalDirs map[string]string
+	// OutputDir receives the result of local and tar outputs (ImageBuildOptions.Outputs)
+	OutputDir string
+	// AllAuthConfigs sends the credentials of all registries instead of those referenced by the Dockerfile
+	AllAuthConfigs bool
+}
+
+type Opt func(*Options)
+
+// NeedsSession reports whether a BuildKit session has to be attached to the build
+func (o Options) NeedsSession() bool {
+	return len(o.Secrets) > 0 || len(o.SSH) > 0 || len(o.LocalDirs) > 0 || o.OutputDir != ""
+}
+
+func RenderOptions(defaults Options, opts []Opt) Options {
+	options := defaults
+	options.Secrets = slices.Clip(options.Secrets)
+	options.SSH = slices.Clip(options.SSH)
+	options.LocalDirs = maps.Clone(options.LocalDirs)
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithSecret serves the content of file as secret id (RUN --mount=type=secret,id=...)
+func WithSecret(id, file string) Opt {
+	return func(o *Options) {
+		o.Secrets = append(o.Secrets, Secret{ID: id, File: file})
+	}
+}
+
+// WithEnvSecret serves the value of the environment variable env as secret id
+func WithEnvSecret(id, env string) Opt {
+	return func(o *Options) {
+		o.Secrets = append(o.Secrets, Secret{ID: id, Env: env})
+	}
+}
+
+// WithSSH forwards an SSH agent as id (RUN --mount=type=ssh,id=...), paths are agent sockets or private keys.
+// The agent at $SSH_AUTH_SOCK is used if no paths are given.
+func WithSSH(id string, paths ...string) Opt {
+	return func(o *Options) {
+		o.SSH = append(o.SSH, SSHAgent{ID: id, Paths: paths})
+	}
+}
+
+// WithLocalDir makes dir available to the builder as name, e.g. as named build context
+func WithLocalDir(name, dir string) Opt {
+	return func(o *Options) {
+		if o.LocalDirs == nil {
+			o.LocalDirs = map[string]string{}
+		}
+		o.LocalDirs[name] = dir
+	}
+}
+
+// WithContextDir syncs the build context and the directory of the Dockerfile through the session instead of
+// uploading a tar, only the files the build uses are transferred
+func WithContextDir(contextDir, dockerfileDir string) Opt {
+	return func(o *Options) {
+		WithLocalDir(ContextSessionDir, contextDir)(o)
+		WithLocalDir(DockerfileSessionDir, dockerfileDir)(o)
+	}
+}
+
+// WithOutputDir writes the result of local and tar outputs to dir
+func WithOutputDir(dir string) Opt {
+	return func(o *Options) {
+		o.OutputDir = dir
+	}
+}
+
+// WithAllAuthConfigs sends the credentials of all registries known to the auth provider to the engine.
+// By default, only those of registries referenced by the Dockerfile and CacheFrom are sent.
+func WithAllAuthConfigs() Opt {
+	return func(o *Options) {
+		o.AllAuthConfigs = true
+	}
+}
@@ -0,0 +1,78 @@
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/moby/go-archive/compression"
+)
+
+// maxPeekSize limits how much of a context is buffered while looking for the Dockerfile
+const maxPeekSize = 64 << 20
+
+// PeekDockerfile reads the Dockerfile name from a (possibly compressed) build context tar.
+// The returned reader yields the complete, decompressed context. The content is nil if the Dockerfile
+// is not within the first 64MiB of the context.
+func PeekDockerfile(buildContext io.Reader, name string) ([]byte, io.Reader, error) {
+	decompressed, err := compression.DecompressStream(buildContext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read build context: %w", err)
+	}
+	name = path.Clean(name)
+
+	var buffer bytes.Buffer
+	reader := tar.NewReader(&peekReader{source: decompressed, buffer: &buffer})
+	rest := func() io.Reader {
+		return &readCloser{Reader: io.MultiReader(&buffer, decompressed), Closer: decompressed}
+	}
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, errPeekLimit) {
+			return nil, rest(), nil
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to read build context: %w", err)
+		}
+		if path.Clean(header.Name) != name || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(reader)
+		if errors.Is(err, errPeekLimit) {
+			return nil, rest(), nil
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to read Dockerfile: %w", err)
+		}
+		return content, rest(), nil
+	}
+}
+
+// errPeekLimit stops the tar reader once maxPeekSize bytes are buffered
+var errPeekLimit = errors.New("peek limit reached")
+
+// peekReader copies what is read from source to buffer, up to maxPeekSize. Skipping a tar entry reads through it,
+// so the limit has to apply to each read, not only between entries.
+type peekReader struct {
+	source io.Reader
+	buffer *bytes.Buffer
+}
+
+func (p *peekReader) Read(b []byte) (int, error) {
+	remaining := maxPeekSize - p.buffer.Len()
+	if remaining <= 0 {
+		return 0, errPeekLimit
+	}
+	if len(b) > remaining {
+		b = b[:remaining]
+	}
+	n, err := p.source.Read(b)
+	p.buffer.Write(b[:n])
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,67 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder/events"
+)
+
+// maxLineSize bounds a single message of the build stream, BuildKit trace messages can get large
+const maxLineSize = 16 << 20
+
+// ParseStream decodes the JSON message stream of an image build into events.
+// Malformed lines are reported as *events.DecodeError and unrecognised messages as *events.UnknownEvent,
+// the channel is closed when the reader is exhausted or ctx is done.
+func ParseStream(ctx context.Context, reader io.ReadCloser) chan events.BuildEvent {
+	result := make(chan events.BuildEvent)
+	go parseEvents(ctx, reader, result)
+	return result
+}
+
+func parseEvents(ctx context.Context, reader io.ReadCloser, ch chan events.BuildEvent) {
+	defer close(ch)
+	defer func() { _ = reader.Close() }()
+
+	send := func(event events.BuildEvent) bool {
+		select {
+		case ch <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	scan := bufio.NewScanner(reader)
+	scan.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scan.Scan() {
+		line := scan.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		parsed, err := parseLine(line)
+		if err != nil {
+			parsed = []events.BuildEvent{&events.DecodeError{Line: bytes.Clone(line), Err: err}}
+		}
+		for _, event := range parsed {
+			if !send(event) {
+				return
+			}
+		}
+	}
+	if err := scan.Err(); err != nil && ctx.Err() == nil {
+		send(&events.DecodeError{Err: err})
+	}
+}
+
+func parseLine(line []byte) ([]events.BuildEvent, error) {
+	var raw jsonmessage.JSONMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, err
+	}
+	return events.Parse(raw)
+}
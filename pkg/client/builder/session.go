@@ -0,0 +1,143 @@
+package builder
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/filesync"
+	"github.com/moby/buildkit/session/secrets/secretsprovider"
+	"github.com/moby/buildkit/session/sshforward/sshprovider"
+	"github.com/moby/go-archive"
+	"github.com/moby/go-archive/compression"
+	"github.com/tonistiigi/fsutil"
+)
+
+type Secret struct {
+	ID string
+	// File is read when the builder requests the secret
+	File string
+	// Env names an environment variable, the variable named like ID is used if neither File nor Env is set
+	Env string
+}
+
+// Value reads the secret
+func (s Secret) Value() ([]byte, error) {
+	if s.File != "" {
+		return os.ReadFile(s.File)
+	}
+	env := s.Env
+	if env == "" {
+		env = s.ID
+	}
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		return nil, fmt.Errorf("secret %s: environment variable %s is not set", s.ID, env)
+	}
+	return []byte(value), nil
+}
+
+type SSHAgent struct {
+	ID    string
+	Paths []string
+}
+
+// NewSession creates a BuildKit session serving the secrets, SSH agents and directories of options.
+// The caller has to run it with a dialer to the engine's /session endpoint and close it after the build.
+func NewSession(ctx context.Context, options Options) (*session.Session, error) {
+	sess, err := session.NewSession(ctx, sharedKey(options.LocalDirs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build session: %w", err)
+	}
+
+	if len(options.Secrets) > 0 {
+		sources := make([]secretsprovider.Source, 0, len(options.Secrets))
+		for _, secret := range options.Secrets {
+			source := secretsprovider.Source{ID: secret.ID, FilePath: secret.File, Env: secret.Env}
+			if secret.File == "" && secret.Env == "" {
+				source.Env = secret.ID
+			}
+			sources = append(sources, source)
+		}
+		store, err := secretsprovider.NewStore(sources)
+		if err != nil {
+			return nil, fmt.Errorf("invalid build secrets: %w", err)
+		}
+		sess.Allow(secretsprovider.NewSecretProvider(store))
+	}
+
+	if len(options.SSH) > 0 {
+		configs := make([]sshprovider.AgentConfig, 0, len(options.SSH))
+		for _, agent := range options.SSH {
+			id := agent.ID
+			if id == "" {
+				id = "default"
+			}
+			configs = append(configs, sshprovider.AgentConfig{ID: id, Paths: agent.Paths})
+		}
+		provider, err := sshprovider.NewSSHAgentProvider(configs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up ssh forwarding: %w", err)
+		}
+		sess.Allow(provider)
+	}
+
+	if len(options.LocalDirs) > 0 {
+		dirs := filesync.StaticDirSource{}
+		for name, dir := range options.LocalDirs {
+			fs, err := fsutil.NewFS(dir)
+			if err != nil {
+				return nil, fmt.Errorf("invalid local directory %s: %w", name, err)
+			}
+			dirs[name] = fs
+		}
+		sess.Allow(filesync.NewFSSyncProvider(dirs))
+	}
+
+	if options.OutputDir != "" {
+		sess.Allow(filesync.NewFSSyncTarget(filesync.WithFSSyncDir(0, options.OutputDir)))
+	}
+	return sess, nil
+}
+
+// sharedKey lets the builder reuse the file sync cache of previous sessions with the same directories
+func sharedKey(dirs map[string]string) string {
+	hash := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(dirs)) {
+		_, _ = fmt.Fprintf(hash, "%s=%s\n", name, dirs[name])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// WithSecretFiles adds the secrets to a (possibly compressed) build context for engines without session support
+// and returns their buildah secret specs (id=...,src=...), with src relative to the context
+func WithSecretFiles(buildContext io.Reader, secrets []Secret) (io.ReadCloser, []string, error) {
+	decompressed, err := compression.DecompressStream(buildContext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read build context: %w", err)
+	}
+	modTime := time.Now()
+	mods := map[string]archive.TarModifierFunc{}
+	specs := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		value, err := secret.Value()
+		if err != nil {
+			_ = decompressed.Close()
+			return nil, nil, err
+		}
+		name := ".build-secret." + randomSuffix()
+		mods[name] = func(_ string, _ *tar.Header, _ io.Reader) (*tar.Header, []byte, error) {
+			return &tar.Header{Name: name, Mode: 0o600, ModTime: modTime, Typeflag: tar.TypeReg}, value, nil
+		}
+		specs = append(specs, fmt.Sprintf("id=%s,src=%s", secret.ID, name))
+	}
+	return archive.ReplaceFileTarWrapper(decompressed, mods), specs, nil
+}
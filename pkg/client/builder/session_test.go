@@ -0,0 +1,38 @@
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSecretFiles(t *testing.T) {
+	t.Setenv("API_TOKEN", "token-value")
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	writer := tar.NewWriter(gz)
+	require.NoError(t, writer.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0o644, Size: 11}))
+	_, err := writer.Write([]byte("FROM alpine"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, gz.Close())
+
+	reader, specs, err := WithSecretFiles(&buf, []Secret{{ID: "API_TOKEN"}})
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	require.True(t, strings.HasPrefix(specs[0], "id=API_TOKEN,src=.build-secret."))
+
+	files := readContext(t, &Context{reader: reader})
+	require.Equal(t, "FROM alpine", files["Dockerfile"])
+	require.Equal(t, "token-value", files[strings.TrimPrefix(specs[0], "id=API_TOKEN,src=")])
+}
+
+func TestSecretValueMissingEnv(t *testing.T) {
+	_, err := Secret{ID: "DOES_NOT_EXIST_FOR_SURE"}.Value()
+	require.Error(t, err)
+}
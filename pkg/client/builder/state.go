@@ -0,0 +1,58 @@
+package builder
+
+import (
+	"context"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder/events"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder/state"
+	"go.uber.org/zap"
+)
+
+// StateFromStream folds the build events into build states.
+// Events which are not valid in the current state are logged and skipped instead of aborting the stream.
+// A build in progress is completed when the stream ends after the image id was reported.
+func StateFromStream(ctx context.Context, ch chan events.BuildEvent, logger *zap.SugaredLogger) chan state.Build {
+	out := make(chan state.Build)
+
+	go processEvents(ctx, ch, out, logger)
+
+	return out
+}
+
+func processEvents(ctx context.Context, ch chan events.BuildEvent, out chan state.Build, logger *zap.SugaredLogger) {
+	defer close(out)
+	send := func(build state.Build) bool {
+		select {
+		case out <- build:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var current state.Build
+	var err error
+	for event := range ch {
+		if decodeErr, ok := event.(*events.DecodeError); ok {
+			logger.Warnf("skipping undecodable build event: %s", decodeErr.String())
+			continue
+		}
+		var next state.Build
+		if current == nil {
+			next, err = state.NewBuildState(event)
+		} else {
+			next, err = current.Next(event)
+		}
+		if err != nil {
+			logger.Warnf("skipping build event %q: %v", event.String(), err)
+			continue
+		}
+		current = next
+		if !send(current) {
+			return
+		}
+	}
+	if inProgress, ok := current.(*state.BuildInProgress); ok && inProgress.ImageID() != "" && ctx.Err() == nil {
+		send(inProgress.Complete())
+	}
+}
@@ -0,0 +1,283 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder/events"
+)
+
+// now is replaced in tests
+var now = time.Now
+
+type Build interface {
+	// Steps returns all steps in the order they were started
+	Steps() []Step
+	Step(id string) (Step, bool)
+	// ImageID is the full id of the built image, it is set shortly before the build completes
+	ImageID() string
+	Tags() []string
+	Warnings() []events.VertexWarning
+	Next(event events.BuildEvent) (Build, error)
+	Status() string
+	Base() BuildBase
+	StartedAt() time.Time
+	// UpdatedAt is the time of the last state transition
+	UpdatedAt() time.Time
+}
+
+type BuildBase struct {
+	steps    []Step
+	imageID  string
+	tags     []string
+	warnings []events.VertexWarning
+	// current is the index of the running classic/podman step, -1 if there is none
+	current int
+
+	startedAt time.Time
+	updatedAt time.Time
+}
+
+func (b *BuildBase) Base() BuildBase {
+	return *b
+}
+
+func (b *BuildBase) Steps() []Step {
+	return slices.Clone(b.steps)
+}
+
+func (b *BuildBase) Step(id string) (Step, bool) {
+	if idx := b.indexOf(id); idx >= 0 {
+		return b.steps[idx], true
+	}
+	return Step{}, false
+}
+
+func (b *BuildBase) ImageID() string {
+	return b.imageID
+}
+
+func (b *BuildBase) Tags() []string {
+	return slices.Clone(b.tags)
+}
+
+func (b *BuildBase) Warnings() []events.VertexWarning {
+	return slices.Clone(b.warnings)
+}
+
+func (b *BuildBase) StartedAt() time.Time {
+	return b.startedAt
+}
+
+func (b *BuildBase) UpdatedAt() time.Time {
+	return b.updatedAt
+}
+
+// CachedSteps counts the steps taken from the build cache
+func (b *BuildBase) CachedSteps() int {
+	count := 0
+	for _, s := range b.steps {
+		if s.Cached {
+			count++
+		}
+	}
+	return count
+}
+
+func (b *BuildBase) indexOf(id string) int {
+	return slices.IndexFunc(b.steps, func(s Step) bool { return s.ID == id })
+}
+
+// with returns a copy of b with the step at idx replaced (or appended if idx is -1) and the index of the step
+func (b BuildBase) with(idx int, step Step) (BuildBase, int) {
+	b.steps = slices.Clone(b.steps)
+	if idx < 0 {
+		b.steps = append(b.steps, step)
+		return b, len(b.steps) - 1
+	}
+	b.steps[idx] = step
+	return b, idx
+}
+
+// completeCurrent completes the running classic/podman step
+func (b BuildBase) completeCurrent(at time.Time) BuildBase {
+	if b.current < 0 {
+		return b
+	}
+	b, _ = b.with(b.current, b.steps[b.current].complete(at))
+	b.current = -1
+	return b
+}
+
+// apply folds an event into the base, it is shared by all states which accept step events
+func (b BuildBase) apply(event events.BuildEvent) (BuildBase, error) {
+	at := now()
+	b.updatedAt = at
+
+	switch event := event.(type) {
+	case *events.StepStarted:
+		b = b.completeCurrent(at)
+		step := Step{
+			ID:        fmt.Sprintf("step-%d", event.Number),
+			Number:    event.Number,
+			Total:     event.Total,
+			Name:      event.Instruction,
+			StartedAt: at,
+		}
+		b, b.current = b.with(b.indexOf(step.ID), step)
+	case *events.StepCached:
+		if b.current >= 0 {
+			step := b.steps[b.current]
+			step.Cached = true
+			if event.ImageID != "" {
+				step.ImageID = event.ImageID
+			}
+			b, _ = b.with(b.current, step)
+		}
+	case *events.StepRunning:
+		if b.current >= 0 {
+			step := b.steps[b.current]
+			step.ContainerID = event.ContainerID
+			b, _ = b.with(b.current, step)
+		}
+	case *events.StepCommitted:
+		if b.current >= 0 {
+			step := b.steps[b.current]
+			step.ImageID = event.ImageID
+			b, _ = b.with(b.current, step)
+			b = b.completeCurrent(at)
+		}
+	case *events.Stream:
+		if b.current >= 0 {
+			b, _ = b.with(b.current, b.steps[b.current].withLog(Log{Stream: 1, Data: []byte(event.Text), Timestamp: at}))
+		}
+	case *events.Vertex:
+		idx := b.indexOf(event.Digest)
+		step := newVertexStep(event)
+		if idx >= 0 {
+			step = b.steps[idx]
+		}
+		b, _ = b.with(idx, step.withVertex(event))
+	case *events.VertexStatus:
+		if idx := b.indexOf(event.Vertex); idx >= 0 {
+			b, _ = b.with(idx, b.steps[idx].withStatus(event))
+		}
+	case *events.VertexLog:
+		if idx := b.indexOf(event.Vertex); idx >= 0 {
+			log := Log{Stream: event.Stream, Data: event.Data, Timestamp: event.Timestamp}
+			b, _ = b.with(idx, b.steps[idx].withLog(log))
+		}
+	case *events.VertexWarning:
+		b.warnings = append(slices.Clone(b.warnings), *event)
+	case *events.ImageID:
+		b.imageID = event.ID
+	case *events.Built:
+		b = b.completeCurrent(at)
+		if b.imageID == "" {
+			b.imageID = event.ImageID
+		}
+	case *events.Tagged:
+		b.tags = append(slices.Clone(b.tags), event.Tag)
+	case *events.Progress, *events.UnknownEvent:
+	default:
+		return b, fmt.Errorf("invalid event for build in progress: %s", event.String())
+	}
+	return b, nil
+}
+
+func NewBuildState(event events.BuildEvent) (Build, error) {
+	at := now()
+	base := BuildBase{current: -1, startedAt: at, updatedAt: at}
+	if buildErr, ok := event.(*events.BuildError); ok {
+		return &BuildErrored{BuildBase: base, Error: buildErr.Message, Code: buildErr.Code}, nil
+	}
+	next, err := base.apply(event)
+	if err != nil {
+		return nil, err
+	}
+	return &BuildInProgress{next}, nil
+}
+
+type BuildInProgress struct {
+	BuildBase
+}
+
+func (b *BuildInProgress) Status() string {
+	for i := len(b.steps) - 1; i >= 0; i-- {
+		if b.steps[i].Started() && !b.steps[i].Completed() {
+			return fmt.Sprintf("Building: %s", b.steps[i].Name)
+		}
+	}
+	return "Building"
+}
+
+func (b *BuildInProgress) Next(event events.BuildEvent) (Build, error) {
+	if buildErr, ok := event.(*events.BuildError); ok {
+		base := b.BuildBase
+		if base.current >= 0 {
+			step := base.steps[base.current]
+			step.Error = buildErr.Message
+			base, _ = base.with(base.current, step)
+		}
+		return &BuildErrored{
+			BuildBase: base.completeCurrent(now()),
+			Error:     buildErr.Message,
+			Code:      buildErr.Code,
+		}, nil
+	}
+	next, err := b.apply(event)
+	if err != nil {
+		return nil, err
+	}
+	return &BuildInProgress{next}, nil
+}
+
+// Complete finishes the build when the stream ended without an error
+func (b *BuildInProgress) Complete() *BuildComplete {
+	at := now()
+	base := b.completeCurrent(at)
+	base.updatedAt = at
+	return &BuildComplete{base}
+}
+
+type BuildComplete struct {
+	BuildBase
+}
+
+func (b *BuildComplete) Status() string {
+	return fmt.Sprintf("Built %s (%d/%d steps cached)", b.imageID, b.CachedSteps(), len(b.steps))
+}
+
+func (b *BuildComplete) Next(event events.BuildEvent) (Build, error) {
+	return nil, fmt.Errorf("build is complete, got event: %s", event.String())
+}
+
+type BuildErrored struct {
+	BuildBase
+	Error string
+	Code  int
+}
+
+func (b *BuildErrored) Status() string {
+	return fmt.Sprintf("Build failed: %s", b.Error)
+}
+
+// FailedStep returns the BuildKit vertex or the classic step the build failed in
+func (b *BuildErrored) FailedStep() (Step, bool) {
+	for i := len(b.steps) - 1; i >= 0; i-- {
+		if b.steps[i].Failed() {
+			return b.steps[i], true
+		}
+	}
+	return Step{}, false
+}
+
+func (b *BuildErrored) Next(event events.BuildEvent) (Build, error) {
+	// BuildKit may still report the failed vertexes after the error
+	next, err := b.apply(event)
+	if err != nil {
+		return nil, err
+	}
+	return &BuildErrored{next, b.Error, b.Code}, nil
+}
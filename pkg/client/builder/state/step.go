@@ -0,0 +1,124 @@
+package state
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder/events"
+)
+
+// Step is one build step: a Dockerfile instruction of the classic builder or podman, or a BuildKit vertex
+type Step struct {
+	// ID is the vertex digest for BuildKit, "step-<number>" otherwise
+	ID string
+	// Number and Total are parsed from "[2/5] RUN ..." for BuildKit, internal vertexes have no number
+	Number int
+	Total  int
+	Name   string
+	Inputs []string
+	Cached bool
+	// ImageID is the intermediate image committed by the classic builder or podman
+	ImageID     string
+	ContainerID string
+	StartedAt   time.Time
+	CompletedAt time.Time
+	Error       string
+	Logs        []Log
+	// Statuses holds the latest status of each operation of a BuildKit vertex, in order of appearance
+	Statuses []events.VertexStatus
+}
+
+type Log struct {
+	// Stream is 1 for stdout and 2 for stderr, the classic builder and podman only report stdout
+	Stream    int
+	Data      []byte
+	Timestamp time.Time
+}
+
+func (s Step) Started() bool {
+	return !s.StartedAt.IsZero()
+}
+
+func (s Step) Completed() bool {
+	return !s.CompletedAt.IsZero()
+}
+
+func (s Step) Failed() bool {
+	return s.Error != ""
+}
+
+// Duration is the run time of a completed step, or the time it has been running so far
+func (s Step) Duration() time.Duration {
+	if !s.Started() {
+		return 0
+	}
+	if s.Completed() {
+		return s.CompletedAt.Sub(s.StartedAt)
+	}
+	return now().Sub(s.StartedAt)
+}
+
+func (s Step) Output() string {
+	var size int
+	for _, l := range s.Logs {
+		size += len(l.Data)
+	}
+	result := make([]byte, 0, size)
+	for _, l := range s.Logs {
+		result = append(result, l.Data...)
+	}
+	return string(result)
+}
+
+var vertexNumberPattern = regexp.MustCompile(`^\[(?:[^\]]* )?(\d+)/(\d+)\]`)
+
+func newVertexStep(vertex *events.Vertex) Step {
+	step := Step{ID: vertex.Digest}
+	if match := vertexNumberPattern.FindStringSubmatch(vertex.Name); match != nil {
+		step.Number, _ = strconv.Atoi(match[1])
+		step.Total, _ = strconv.Atoi(match[2])
+	}
+	return step
+}
+
+func (s Step) withVertex(vertex *events.Vertex) Step {
+	s.Name = vertex.Name
+	s.Inputs = vertex.Inputs
+	s.Cached = vertex.Cached
+	if vertex.Started != nil {
+		s.StartedAt = *vertex.Started
+	}
+	if vertex.Completed != nil {
+		s.CompletedAt = *vertex.Completed
+	}
+	s.Error = vertex.Error
+	return s
+}
+
+func (s Step) withStatus(status *events.VertexStatus) Step {
+	statuses := make([]events.VertexStatus, len(s.Statuses), len(s.Statuses)+1)
+	copy(statuses, s.Statuses)
+	s.Statuses = statuses
+	for i := range s.Statuses {
+		if s.Statuses[i].ID == status.ID {
+			s.Statuses[i] = *status
+			return s
+		}
+	}
+	s.Statuses = append(s.Statuses, *status)
+	return s
+}
+
+func (s Step) withLog(log Log) Step {
+	// the slice is shared with previous states, appending must not write into their backing array
+	s.Logs = append(s.Logs[:len(s.Logs):len(s.Logs)], log)
+	return s
+}
+
+func (s Step) complete(at time.Time) Step {
+	if !s.Completed() {
+		s.CompletedAt = at
+	}
+	return s
+}
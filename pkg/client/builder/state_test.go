@@ -0,0 +1,115 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder/events"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder/state"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func foldStream(t *testing.T, input string) state.Build {
+	ctx := context.Background()
+	var last state.Build
+	for build := range StateFromStream(ctx, ParseStream(ctx, io.NopCloser(strings.NewReader(input))), zap.NewNop().Sugar()) {
+		last = build
+	}
+	require.NotNil(t, last)
+	return last
+}
+
+func TestClassicBuildState(t *testing.T) {
+	input := strings.Join([]string{
+		`{"stream":"Step 1/3 : FROM alpine"}`,
+		`{"stream":"\n"}`,
+		`{"stream":" ---> 1d34ffeaf190\n"}`,
+		`{"stream":"Step 2/3 : RUN echo hello\n"}`,
+		`{"stream":" ---> Using cache\n"}`,
+		`{"stream":" ---> 2e45ffeaf190\n"}`,
+		`{"stream":"Step 3/3 : RUN echo world\n"}`,
+		`{"stream":" ---> Running in 6c4a4dbd5bf3\n"}`,
+		`{"stream":"world\n"}`,
+		`not json`,
+		`{"stream":" ---> 3f56ffeaf190\n"}`,
+		`{"aux":{"ID":"sha256:3f56ffeaf19000000000000000000000000000000000000000000000000000000"}}`,
+		`{"stream":"Successfully built 3f56ffeaf190\n"}`,
+		`{"stream":"Successfully tagged test:latest\n"}`,
+	}, "\n")
+
+	build := foldStream(t, input)
+	require.IsType(t, &state.BuildComplete{}, build)
+	require.Equal(t, "sha256:3f56ffeaf19000000000000000000000000000000000000000000000000000000", build.ImageID())
+	require.Equal(t, []string{"test:latest"}, build.Tags())
+
+	steps := build.Steps()
+	require.Len(t, steps, 3)
+	require.False(t, steps[0].Cached)
+	require.True(t, steps[1].Cached)
+	require.Equal(t, "2e45ffeaf190", steps[1].ImageID)
+	require.Equal(t, "RUN echo world", steps[2].Name)
+	require.Equal(t, "6c4a4dbd5bf3", steps[2].ContainerID)
+	require.Equal(t, "world\n", steps[2].Output())
+	for _, step := range steps {
+		require.True(t, step.Completed(), step.Name)
+	}
+}
+
+func TestBuildKitBuildState(t *testing.T) {
+	started := time.Unix(1000, 0)
+	trace := func(status *controlapi.StatusResponse) string {
+		data, err := status.MarshalVT()
+		require.NoError(t, err)
+		aux, err := json.Marshal(data)
+		require.NoError(t, err)
+		return `{"id":"moby.buildkit.trace","aux":` + string(aux) + `}`
+	}
+	input := strings.Join([]string{
+		trace(&controlapi.StatusResponse{Vertexes: []*controlapi.Vertex{
+			{Digest: "sha256:from", Name: "[1/2] FROM docker.io/library/alpine", Cached: true,
+				Started: timestamppb.New(started), Completed: timestamppb.New(started)},
+			{Digest: "sha256:run", Name: "[2/2] RUN false", Started: timestamppb.New(started)},
+		}}),
+		trace(&controlapi.StatusResponse{
+			Logs: []*controlapi.VertexLog{{Vertex: "sha256:run", Stream: 2, Msg: []byte("oops\n")}},
+		}),
+		trace(&controlapi.StatusResponse{Vertexes: []*controlapi.Vertex{
+			{Digest: "sha256:run", Name: "[2/2] RUN false", Started: timestamppb.New(started),
+				Completed: timestamppb.New(started.Add(2 * time.Second)), Error: "exit code: 1"},
+		}}),
+		`{"errorDetail":{"message":"process \"/bin/sh -c false\" did not complete successfully: exit code: 1"}}`,
+	}, "\n")
+
+	build := foldStream(t, input)
+	require.IsType(t, &state.BuildErrored{}, build)
+	steps := build.Steps()
+	require.Len(t, steps, 2)
+	require.True(t, steps[0].Cached)
+	require.Equal(t, 1, steps[0].Number)
+
+	failed, ok := build.(*state.BuildErrored).FailedStep()
+	require.True(t, ok)
+	require.Equal(t, "sha256:run", failed.ID)
+	require.Equal(t, 2, failed.Number)
+	require.Equal(t, 2*time.Second, failed.Duration())
+	require.Equal(t, "oops\n", failed.Output())
+	require.Equal(t, 2, failed.Logs[0].Stream)
+}
+
+func TestParseStreamEvents(t *testing.T) {
+	input := `{"stream":"STEP 1/2: FROM alpine\n"}` + "\n" + `{"stream":"--> Using cache 1234abcd\n"}`
+	var parsed []events.BuildEvent
+	for event := range ParseStream(context.Background(), io.NopCloser(strings.NewReader(input))) {
+		parsed = append(parsed, event)
+	}
+	require.Len(t, parsed, 2)
+	require.Equal(t, &events.StepStarted{Number: 1, Total: 2, Instruction: "FROM alpine"}, parsed[0])
+	require.Equal(t, &events.StepCached{ImageID: "1234abcd"}, parsed[1])
+}
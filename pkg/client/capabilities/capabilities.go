@@ -0,0 +1,150 @@
+package capabilities
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/api/types/versions"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/platform"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/podman/containers/libpod/define"
+)
+
+type EngineKind string
+
+const (
+	EngineDocker EngineKind = "docker"
+	EnginePodman EngineKind = "podman"
+)
+
+// ImageStore is the kind of image store of an engine, it determines the local image ids and the pull progress
+type ImageStore string
+
+const (
+	// ImageStoreClassic is docker's graph driver store, images are identified by their config digest
+	ImageStoreClassic ImageStore = "classic"
+	// ImageStoreContainerd is docker's containerd image store, images are identified by their manifest or index digest
+	ImageStoreContainerd ImageStore = "containerd"
+	// ImageStorePodman is podman's containers/storage, images are identified by their config digest
+	ImageStorePodman ImageStore = "podman"
+)
+
+// podmanComponent is the version component podman reports on the docker compatible API
+const podmanComponent = "Podman Engine"
+
+// containerdSnapshotter is the driver type docker reports when using the containerd image store
+const containerdSnapshotter = "io.containerd.snapshotter.v1"
+
+// buildKitAPIVersion is the first API version supporting BuildKit builds
+const buildKitAPIVersion = "1.39"
+
+// Capabilities describe the engine a client is connected to
+type Capabilities struct {
+	Engine        EngineKind
+	EngineVersion string
+	// APIVersion is the maximum API version of the engine, MinAPIVersion the minimum
+	APIVersion    string
+	MinAPIVersion string
+	// OperatingSystem is the engine host's OS, e.g. "Docker Desktop" or "Fedora Linux 41"
+	OperatingSystem string
+	Rootless        bool
+	// CgroupVersion is "1" or "2"
+	CgroupVersion string
+	StorageDriver string
+	// ContainerdSnapshotter is set if docker uses the containerd image store instead of a graph driver
+	ContainerdSnapshotter bool
+	// BuildKit is set if the engine builds images with BuildKit (podman builds with buildah)
+	BuildKit bool
+	// DefaultPlatform is the native platform of the engine
+	DefaultPlatform v1.Platform
+	// Platforms are the platforms the engine can run containers of, the default platform first.
+	// FromEngine only knows the default platform, emulated platforms are added with AddPlatforms.
+	Platforms       []v1.Platform
+	SecurityOptions []string
+	// RootlessNetwork is podman's rootless network command (slirp4netns or pasta)
+	RootlessNetwork string
+}
+
+// FromEngine derives the capabilities from the engine's responses, podman is only set for podman engines
+func FromEngine(version types.Version, info system.Info, ping types.Ping, podman *define.Info) *Capabilities {
+	result := &Capabilities{
+		Engine:          EngineDocker,
+		EngineVersion:   version.Version,
+		APIVersion:      version.APIVersion,
+		MinAPIVersion:   version.MinAPIVersion,
+		OperatingSystem: info.OperatingSystem,
+		CgroupVersion:   info.CgroupVersion,
+		StorageDriver:   info.Driver,
+		SecurityOptions: info.SecurityOptions,
+		// linux engines support BuildKit on request since API 1.39, even if it is not the default builder
+		BuildKit: ping.BuilderVersion == build.BuilderBuildKit ||
+			(info.OSType == "linux" && versions.GreaterThanOrEqualTo(version.APIVersion, buildKitAPIVersion)),
+		ContainerdSnapshotter: slices.ContainsFunc(info.DriverStatus, func(status [2]string) bool {
+			return status[0] == "driver-type" && status[1] == containerdSnapshotter
+		}),
+	}
+	result.Rootless = result.HasSecurityOption("rootless")
+	if slices.ContainsFunc(version.Components, func(c types.ComponentVersion) bool {
+		return c.Name == podmanComponent
+	}) {
+		result.Engine = EnginePodman
+		result.BuildKit = false
+	}
+	if podman != nil && podman.Host != nil {
+		result.Rootless = podman.Host.Security.Rootless
+		result.RootlessNetwork = podman.Host.RootlessNetworkCmd
+		if result.CgroupVersion == "" {
+			result.CgroupVersion = strings.TrimPrefix(podman.Host.CgroupsVersion, "v")
+		}
+	}
+
+	result.DefaultPlatform = platform.Normalize(v1.Platform{OS: info.OSType, Architecture: info.Architecture})
+	result.Platforms = []v1.Platform{result.DefaultPlatform}
+	return result
+}
+
+func (c *Capabilities) IsPodman() bool {
+	return c.Engine == EnginePodman
+}
+
+// ImageStore returns the kind of image store the engine uses
+func (c *Capabilities) ImageStore() ImageStore {
+	switch {
+	case c.IsPodman():
+		return ImageStorePodman
+	case c.ContainerdSnapshotter:
+		return ImageStoreContainerd
+	default:
+		return ImageStoreClassic
+	}
+}
+
+// AddPlatforms adds platforms the engine runs, e.g. the emulated platforms of its BuildKit workers
+func (c *Capabilities) AddPlatforms(platforms ...v1.Platform) {
+	c.Platforms = platform.Merge(c.Platforms, platforms...)
+}
+
+// CanRun reports whether the engine runs containers of p, natively or emulated
+func (c *Capabilities) CanRun(p v1.Platform) bool {
+	return platform.Runs(c.Platforms, p) || !c.NeedsEmulation(p)
+}
+
+// NeedsEmulation reports whether containers of p don't run natively on the engine
+func (c *Capabilities) NeedsEmulation(p v1.Platform) bool {
+	return !platform.Runs(platform.Native(c.DefaultPlatform), p)
+}
+
+// Desktop is set for Docker Desktop, whose engine runs in a VM
+func (c *Capabilities) Desktop() bool {
+	return strings.Contains(c.OperatingSystem, "Docker Desktop")
+}
+
+// HasSecurityOption checks for a security option by name, e.g. "seccomp", "apparmor", "selinux" or "rootless"
+func (c *Capabilities) HasSecurityOption(name string) bool {
+	return slices.ContainsFunc(c.SecurityOptions, func(option string) bool {
+		return option == name || slices.Contains(strings.Split(option, ","), "name="+name)
+	})
+}
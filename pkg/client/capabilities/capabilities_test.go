@@ -0,0 +1,99 @@
+package capabilities
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/system"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/podman/containers/libpod/define"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromDocker(t *testing.T) {
+	caps := FromEngine(
+		types.Version{Version: "28.3.3", APIVersion: "1.51", MinAPIVersion: "1.24"},
+		system.Info{
+			OSType:          "linux",
+			Architecture:    "aarch64",
+			OperatingSystem: "Docker Desktop",
+			CgroupVersion:   "2",
+			Driver:          "overlayfs",
+			DriverStatus:    [][2]string{{"driver-type", "io.containerd.snapshotter.v1"}},
+			SecurityOptions: []string{"name=seccomp,profile=builtin", "name=cgroupns"},
+		},
+		types.Ping{BuilderVersion: "2"},
+		nil,
+	)
+	require.Equal(t, EngineDocker, caps.Engine)
+	require.Equal(t, "1.51", caps.APIVersion)
+	require.True(t, caps.ContainerdSnapshotter)
+	require.Equal(t, ImageStoreContainerd, caps.ImageStore())
+	require.True(t, caps.BuildKit)
+	require.True(t, caps.Desktop())
+	require.False(t, caps.Rootless)
+	require.True(t, caps.HasSecurityOption("seccomp"))
+	require.False(t, caps.HasSecurityOption("apparmor"))
+	require.Equal(t, v1.Platform{OS: "linux", Architecture: "arm64"}, caps.DefaultPlatform)
+	require.Equal(t, []v1.Platform{caps.DefaultPlatform}, caps.Platforms)
+
+	amd64 := v1.Platform{OS: "linux", Architecture: "amd64"}
+	require.True(t, caps.NeedsEmulation(amd64))
+	require.False(t, caps.CanRun(amd64))
+	caps.AddPlatforms(amd64, v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"})
+	require.Len(t, caps.Platforms, 2)
+	require.True(t, caps.CanRun(amd64))
+	require.False(t, caps.NeedsEmulation(v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}))
+}
+
+func TestFromPodman(t *testing.T) {
+	caps := FromEngine(
+		types.Version{
+			Version:    "5.4.0",
+			APIVersion: "1.41",
+			Components: []types.ComponentVersion{{Name: "Podman Engine", Version: "5.4.0"}},
+		},
+		system.Info{OSType: "linux", Architecture: "x86_64", Driver: "overlay", SecurityOptions: []string{"name=rootless"}},
+		types.Ping{},
+		&define.Info{Host: &define.HostInfo{
+			CgroupsVersion:     "v2",
+			RootlessNetworkCmd: "pasta",
+			Security:           define.SecurityInfo{Rootless: true},
+		}},
+	)
+	require.True(t, caps.IsPodman())
+	require.False(t, caps.BuildKit)
+	require.True(t, caps.Rootless)
+	require.Equal(t, "pasta", caps.RootlessNetwork)
+	require.Equal(t, "2", caps.CgroupVersion)
+	require.False(t, caps.ContainerdSnapshotter)
+	require.Equal(t, ImageStorePodman, caps.ImageStore())
+	require.Equal(t, v1.Platform{OS: "linux", Architecture: "amd64"}, caps.DefaultPlatform)
+}
+
+func TestLoaderTTL(t *testing.T) {
+	var loads atomic.Int32
+	loader := NewLoader(50*time.Millisecond, func(context.Context) (*Capabilities, error) {
+		loads.Add(1)
+		return &Capabilities{}, nil
+	})
+	ctx := context.Background()
+	first, err := loader.Get(ctx)
+	require.NoError(t, err)
+	second, err := loader.Get(ctx)
+	require.NoError(t, err)
+	require.Same(t, first, second)
+	require.Equal(t, int32(1), loads.Load())
+
+	_, err = loader.Refresh(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), loads.Load())
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = loader.Get(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), loads.Load())
+}
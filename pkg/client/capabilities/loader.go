@@ -0,0 +1,51 @@
+package capabilities
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is the time capabilities are cached before they are loaded again
+const DefaultTTL = 5 * time.Minute
+
+// Loader loads the capabilities lazily and caches them for its TTL
+type Loader struct {
+	mutex    sync.Mutex
+	ttl      time.Duration
+	load     func(ctx context.Context) (*Capabilities, error)
+	value    *Capabilities
+	loadedAt time.Time
+}
+
+// NewLoader creates a loader, a ttl <= 0 caches the capabilities forever
+func NewLoader(ttl time.Duration, load func(ctx context.Context) (*Capabilities, error)) *Loader {
+	return &Loader{ttl: ttl, load: load}
+}
+
+// Get returns the cached capabilities, or loads them if there are none or they expired.
+// Concurrent callers wait for a single load.
+func (l *Loader) Get(ctx context.Context) (*Capabilities, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.value != nil && (l.ttl <= 0 || time.Since(l.loadedAt) < l.ttl) {
+		return l.value, nil
+	}
+	return l.refresh(ctx)
+}
+
+// Refresh loads the capabilities again, e.g. after the engine was reconfigured
+func (l *Loader) Refresh(ctx context.Context) (*Capabilities, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.refresh(ctx)
+}
+
+func (l *Loader) refresh(ctx context.Context) (*Capabilities, error) {
+	value, err := l.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l.value, l.loadedAt = value, time.Now()
+	return value, nil
+}
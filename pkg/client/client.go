@@ -1,32 +1,52 @@
 package client
 
 import (
+	"context"
 	"log/slog"
-	"net"
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/docker/docker/client"
 	client2 "github.com/docker/go-sdk/client"
 	"github.com/silenium-dev/docker-wrapper/pkg/api"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/builder"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/hostip"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/mirror"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/provider"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull"
 	"go.uber.org/zap"
 	"go.uber.org/zap/exp/zapslog"
 )
 
 type Client struct {
 	api.DockerClient
-	sdkClient              *client2.Client
-	dockerOpts             []client.Opt
-	authProvider           provider.AuthProvider
-	imageProvider          provider.ImageProvider
-	logger                 *zap.SugaredLogger
-	hostFromContainerAddr  net.IP
-	hostFromContainerMutex sync.RWMutex
+	sdkClient     *client2.Client
+	dockerOpts    []client.Opt
+	authProvider  provider.AuthProvider
+	imageProvider provider.ImageProvider
+	logger        *zap.SugaredLogger
+	pullDefaults  pull.Options
+	buildDefaults builder.Options
+	rewriter      *mirror.Rewriter
+	hostIPs       *hostip.Cache
+	capabilities  *capabilities.Loader
+	// capabilitiesTTL is set by WithCapabilitiesTTL, capabilities.DefaultTTL if nil
+	capabilitiesTTL *time.Duration
+	hostIPWatch     sync.Once
+	// platformProbe is set by WithPlatformProbe
+	platformProbe bool
+	// probedPlatforms caches the result of the platform probe container, see SystemPlatforms
+	probedPlatforms *probedPlatforms
+	platformsMutex  sync.Mutex
+	// closeCtx lives until Close, for background work of the client
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
 }
 
 func NewWithOpts(opts ...Opt) (*Client, error) {
-	c := &Client{}
+	c := &Client{hostIPs: hostip.NewCache()}
 	for _, opt := range opts {
 		err := opt(c)
 		if err != nil {
@@ -57,6 +77,12 @@ func NewWithOpts(opts ...Opt) (*Client, error) {
 		zapslog.AddStacktraceAt(slog.LevelError),
 	))
 	c.sdkClient = result
+	ttl := capabilities.DefaultTTL
+	if c.capabilitiesTTL != nil {
+		ttl = *c.capabilitiesTTL
+	}
+	c.capabilities = capabilities.NewLoader(ttl, c.loadCapabilities)
+	c.closeCtx, c.closeCancel = context.WithCancel(context.Background())
 
 	return c, nil
 }
@@ -3,9 +3,12 @@ package client
 import (
 	"net/http"
 	"slices"
+	"time"
 
 	"github.com/docker/docker/client"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/mirror"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/provider"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull"
 	"go.uber.org/zap"
 )
 
@@ -44,6 +47,87 @@ func WithImageProvider(imageProvider provider.ImageProvider) Opt {
 	}
 }
 
+// WithPullRetryPolicy retries failed image pulls by default, can be overridden per call with pull.WithRetry and
+// pull.WithoutRetry
+func WithPullRetryPolicy(policy pull.RetryPolicy) Opt {
+	return func(c *Client) error {
+		c.pullDefaults.Retry = &policy
+		return nil
+	}
+}
+
+// WithPullPolicy sets the default pull policy of the ImagePull* methods, can be overridden per call with
+// pull.WithPolicy
+func WithPullPolicy(policy pull.Policy) Opt {
+	return func(c *Client) error {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		c.pullDefaults.Policy = policy
+		return nil
+	}
+}
+
+// WithPullVerifier verifies images before every pull, can be overridden per call with pull.WithVerifier
+func WithPullVerifier(verifier pull.Verifier) Opt {
+	return func(c *Client) error {
+		c.pullDefaults.Verifier = verifier
+		return nil
+	}
+}
+
+// WithBuildAllAuthConfigs sends the credentials of all registries with every build, instead of only those of the
+// registries referenced by the Dockerfile. Can be enabled per call with builder.WithAllAuthConfigs.
+func WithBuildAllAuthConfigs() Opt {
+	return func(c *Client) error {
+		c.buildDefaults.AllAuthConfigs = true
+		return nil
+	}
+}
+
+// WithMirrors rewrites image references of pulls, manifest lookups and build cache images to mirrors.
+// Images pulled from a mirror are tagged with their original reference. The engine resolves the images of a
+// Dockerfile itself, so they are pulled through their mirrors before the build, unless PullParent is set or they are
+// pinned by digest.
+func WithMirrors(rules ...mirror.Rule) Opt {
+	return func(c *Client) error {
+		var existing []mirror.Rule
+		if c.rewriter != nil {
+			existing = c.rewriter.Rules()
+		}
+		c.rewriter = mirror.NewRewriter(slices.Concat(existing, rules)...)
+		return nil
+	}
+}
+
+// WithRegistriesConf reads mirror rules from a containers registries.conf file, see WithMirrors
+func WithRegistriesConf(path string) Opt {
+	return func(c *Client) error {
+		rules, err := mirror.LoadRegistriesConf(path)
+		if err != nil {
+			return err
+		}
+		return WithMirrors(rules...)(c)
+	}
+}
+
+// WithCapabilitiesTTL sets how long the engine capabilities are cached, 0 caches them for the client's lifetime
+func WithCapabilitiesTTL(ttl time.Duration) Opt {
+	return func(c *Client) error {
+		c.capabilitiesTTL = &ttl
+		return nil
+	}
+}
+
+// WithPlatformProbe lets SystemPlatforms run the provider's binfmt image in a privileged container, if the engine's
+// emulated platforms can't be discovered otherwise
+func WithPlatformProbe() Opt {
+	return func(c *Client) error {
+		c.platformProbe = true
+		return nil
+	}
+}
+
 func WithDockerOpts(opts ...client.Opt) Opt {
 	return func(c *Client) error {
 		c.dockerOpts = slices.Concat(c.dockerOpts, opts)
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/moby/term"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+)
+
+// ContainerAttachStream attaches to a container and returns a full-duplex stream.
+// The stream is always attached in streaming mode, stdin is only forwarded if options.Stdin is set.
+func (c *Client) ContainerAttachStream(
+	ctx context.Context, id string, options container.AttachOptions,
+) (*stream.Duplex, error) {
+	inspect, err := c.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := detachKeys(options.DetachKeys)
+	if err != nil {
+		return nil, err
+	}
+	options.Stream = true
+	response, err := c.ContainerAttach(ctx, id, options)
+	if err != nil {
+		return nil, err
+	}
+	return stream.NewDuplexStream(ctx, response.Conn, response.Reader, isMultiplexed(response, !inspect.Config.Tty),
+		stream.Control{
+			Resize: func(ctx context.Context, height, width uint) error {
+				return c.ContainerResize(ctx, id, container.ResizeOptions{Height: height, Width: width})
+			},
+			DetachKeys: keys,
+		}, c.logger), nil
+}
+
+// ContainerExecAttachStream starts a created exec and returns a full-duplex stream to it.
+// keys must match the DetachKeys the exec was created with, empty for the default.
+func (c *Client) ContainerExecAttachStream(
+	ctx context.Context, execID string, options container.ExecAttachOptions, keys string,
+) (*stream.Duplex, error) {
+	detach, err := detachKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.ContainerExecAttach(ctx, execID, options)
+	if err != nil {
+		return nil, err
+	}
+	return stream.NewDuplexStream(ctx, response.Conn, response.Reader, isMultiplexed(response, !options.Tty),
+		stream.Control{
+			Resize: func(ctx context.Context, height, width uint) error {
+				return c.ContainerExecResize(ctx, execID, container.ResizeOptions{Height: height, Width: width})
+			},
+			DetachKeys: detach,
+		}, c.logger), nil
+}
+
+// isMultiplexed uses the media type of the response if the engine reports one (API 1.42+)
+func isMultiplexed(response types.HijackedResponse, fallback bool) bool {
+	if mediaType, ok := response.MediaType(); ok {
+		return mediaType == types.MediaTypeMultiplexedStream
+	}
+	return fallback
+}
+
+func detachKeys(keys string) ([]byte, error) {
+	if keys == "" {
+		return stream.DefaultDetachKeys, nil
+	}
+	parsed, err := term.ToBytes(keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid detach keys %q: %w", keys, err)
+	}
+	return parsed, nil
+}
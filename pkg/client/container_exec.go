@@ -0,0 +1,169 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/exec"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+// execPollInterval is the interval the exit code of a finished exec is polled with
+const execPollInterval = 50 * time.Millisecond
+
+// execKillTimeout bounds the cleanup of a cancelled exec
+const execKillTimeout = 5 * time.Second
+
+// execMarkerEnv is set to a random value in the environment of every exec, to find its processes in the container
+const execMarkerEnv = "DOCKER_WRAPPER_EXEC"
+
+// ContainerExec runs cmd in a container and waits for it to exit.
+// Output is captured in the result unless it is streamed with exec.WithOutput.
+func (c *Client) ContainerExec(ctx context.Context, id string, cmd []string, opts ...exec.Opt) (*exec.Result, error) {
+	options := exec.RenderOptions(opts)
+	process, err := c.containerExecStream(ctx, id, cmd, options)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = process.Close() }()
+
+	var stdout, stderr bytes.Buffer
+	outWriter, errWriter := io.Writer(&stdout), io.Writer(&stderr)
+	if options.Stdout != nil {
+		outWriter = options.Stdout
+	}
+	if options.Stderr != nil {
+		errWriter = options.Stderr
+	}
+	var writeErr error
+	for msg := range process.Messages() {
+		if writeErr != nil {
+			continue
+		}
+		switch msg.StreamType {
+		case stream.TypeStdout:
+			_, writeErr = outWriter.Write(msg.Content)
+		case stream.TypeStderr:
+			_, writeErr = errWriter.Write(msg.Content)
+		}
+	}
+	if writeErr != nil {
+		return nil, fmt.Errorf("failed to write output of exec %s: %w", process.ID, writeErr)
+	}
+
+	exitCode, err := process.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &exec.Result{ExitCode: exitCode, Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}, nil
+}
+
+// ContainerExecStream starts cmd in a container and returns the running process.
+// Cancelling ctx closes the stream and kills the process: the engine API can't signal execs, so processes carrying
+// the exec's marker variable (see execMarkerEnv) are killed by a second exec in the container, if it has a shell.
+func (c *Client) ContainerExecStream(
+	ctx context.Context, id string, cmd []string, opts ...exec.Opt,
+) (*exec.Process, error) {
+	return c.containerExecStream(ctx, id, cmd, exec.RenderOptions(opts))
+}
+
+func (c *Client) containerExecStream(
+	ctx context.Context, id string, cmd []string, options exec.Options,
+) (*exec.Process, error) {
+	marker := rand.String(16)
+	created, err := c.ContainerExecCreate(ctx, id, container.ExecOptions{
+		User:         options.User,
+		Privileged:   options.Privileged,
+		Tty:          options.Tty,
+		AttachStdin:  options.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          append(slices.Clone(options.Env), execMarkerEnv+"="+marker),
+		WorkingDir:   options.WorkingDir,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec in container %s: %w", id, err)
+	}
+	duplex, err := c.ContainerExecAttachStream(ctx, created.ID, container.ExecAttachOptions{Tty: options.Tty}, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start exec %s: %w", created.ID, err)
+	}
+
+	if options.Stdin != nil {
+		go func() {
+			if _, err := io.Copy(duplex, options.Stdin); err != nil {
+				c.logger.Debugf("failed to copy stdin to exec %s: %v", created.ID, err)
+			}
+			if err := duplex.CloseWrite(); err != nil {
+				c.logger.Debugf("failed to close stdin of exec %s: %v", created.ID, err)
+			}
+		}()
+	}
+	go func() {
+		select {
+		case <-duplex.Done():
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			c.killExec(id, created.ID, marker)
+		}
+	}()
+
+	return exec.NewProcess(created.ID, duplex, func(ctx context.Context) (int, error) {
+		return c.execExitCode(ctx, created.ID)
+	}), nil
+}
+
+// execExitCode polls the exec until the engine reports it as stopped, which may lag behind the end of the output
+func (c *Client) execExitCode(ctx context.Context, execID string) (int, error) {
+	ticker := time.NewTicker(execPollInterval)
+	defer ticker.Stop()
+	for {
+		inspect, err := c.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect exec %s: %w", execID, err)
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// killExec kills the processes of a cancelled exec from inside the container, they are found by their marker
+func (c *Client) killExec(containerID, execID, marker string) {
+	ctx, cancel := context.WithTimeout(context.Background(), execKillTimeout)
+	defer cancel()
+	inspect, err := c.ContainerExecInspect(ctx, execID)
+	if err != nil {
+		c.logger.Debugf("failed to inspect cancelled exec %s: %v", execID, err)
+		return
+	}
+	if !inspect.Running {
+		return
+	}
+	script := fmt.Sprintf(
+		`for p in /proc/[0-9]*; do tr '\0' '\n' < "$p/environ" 2>/dev/null | grep -qx '%s=%s' && kill -9 "${p#/proc/}"; done`,
+		execMarkerEnv, marker,
+	)
+	killer, err := c.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		User: "root", Cmd: []string{"sh", "-c", script},
+	})
+	if err == nil {
+		err = c.ContainerExecStart(ctx, killer.ID, container.ExecStartOptions{Detach: true})
+	}
+	if err != nil {
+		c.logger.Debugf("failed to kill cancelled exec %s, it may keep running: %v", execID, err)
+	}
+}
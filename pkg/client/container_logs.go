@@ -2,20 +2,68 @@ package client
 
 import (
 	"context"
+	"io"
+	"slices"
+	"time"
 
-	"github.com/docker/docker/api/types/container"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/logs"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
 )
 
 func (c *Client) StreamLogs(ctx context.Context, id string, follow bool) (*stream.MultiplexedStream, error) {
+	var opts []logs.Opt
+	if follow {
+		opts = append(opts, logs.WithFollow())
+	}
+	return c.ContainerLogsStream(ctx, id, opts...)
+}
+
+// ContainerLogsStream streams the logs of a container, by default all lines of stdout and stderr
+func (c *Client) ContainerLogsStream(ctx context.Context, id string, opts ...logs.Opt) (
+	*stream.MultiplexedStream, error,
+) {
+	options := logs.RenderOptions(opts)
 	inspect, err := c.ContainerInspect(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	reader, err := c.ContainerLogs(ctx, id, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: follow})
+	reader, err := c.ContainerLogs(ctx, id, options.LogsOptions())
 	if err != nil {
 		return nil, err
 	}
-	return stream.NewMultiplexedStream(ctx, reader, reader, nil, !inspect.Config.Tty, c.Logger()), nil
+	streamOpts := slices.Clone(options.Stream)
+	if options.Timestamps || options.Resume {
+		streamOpts = append(streamOpts, stream.WithTimestamps())
+	}
+	if options.Resume && options.Follow {
+		streamOpts = append(streamOpts, stream.WithReopen(c.reopenLogs(id, options)))
+	}
+	return stream.NewMultiplexedStream(ctx, reader, reader, nil, !inspect.Config.Tty, c.Logger(), streamOpts...), nil
+}
+
+// reopenLogs resumes following the logs from the last timestamp. Once the container stopped, the remaining
+// lines are read once without following.
+func (c *Client) reopenLogs(id string, options logs.Options) stream.Reopen {
+	final := false
+	return func(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+		if final {
+			return nil, nil
+		}
+		inspect, err := c.ContainerInspect(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		resumed := options
+		if !since.IsZero() {
+			resumed.Since = since
+			resumed.Tail = logs.TailAll
+		}
+		if !inspect.State.Running {
+			final = true
+			resumed.Follow = false
+		}
+		c.logger.Debugf("resuming logs of container %s since %s", id, since.Format(time.RFC3339Nano))
+		return c.ContainerLogs(ctx, id, resumed.LogsOptions())
+	}
 }
@@ -0,0 +1,12 @@
+package client
+
+import (
+	"context"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/wait"
+)
+
+// ContainerWaitReady blocks until strategy reports the container as ready
+func (c *Client) ContainerWaitReady(ctx context.Context, id string, strategy wait.Strategy) error {
+	return strategy.WaitUntilReady(ctx, c, id)
+}
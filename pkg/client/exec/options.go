@@ -0,0 +1,86 @@
+package exec
+
+import "io"
+
+// Options configure a command run with the wrapper's ContainerExec* methods
+type Options struct {
+	Env        []string
+	WorkingDir string
+	User       string
+	Privileged bool
+	// Tty allocates a pseudo-TTY, stdout and stderr are then merged into stdout
+	Tty bool
+	// Stdin is copied to the process, stdin is closed when it is exhausted
+	Stdin io.Reader
+	// Stdout and Stderr receive the output as it arrives instead of capturing it in the Result
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+type Opt func(*Options)
+
+// WithEnv adds environment variables in KEY=value form
+func WithEnv(env ...string) Opt {
+	return func(o *Options) {
+		o.Env = append(o.Env, env...)
+	}
+}
+
+// WithWorkingDir runs the command in dir instead of the container's working directory
+func WithWorkingDir(dir string) Opt {
+	return func(o *Options) {
+		o.WorkingDir = dir
+	}
+}
+
+// WithUser runs the command as user (name, uid or uid:gid)
+func WithUser(user string) Opt {
+	return func(o *Options) {
+		o.User = user
+	}
+}
+
+// WithPrivileged runs the command with extended privileges
+func WithPrivileged() Opt {
+	return func(o *Options) {
+		o.Privileged = true
+	}
+}
+
+// WithTty allocates a pseudo-TTY for the command
+func WithTty() Opt {
+	return func(o *Options) {
+		o.Tty = true
+	}
+}
+
+// WithStdin attaches stdin and copies reader to it
+func WithStdin(reader io.Reader) Opt {
+	return func(o *Options) {
+		o.Stdin = reader
+	}
+}
+
+// WithOutput streams stdout and stderr to the writers, a nil writer discards the stream
+func WithOutput(stdout, stderr io.Writer) Opt {
+	return func(o *Options) {
+		o.Stdout = orDiscard(stdout)
+		o.Stderr = orDiscard(stderr)
+	}
+}
+
+func orDiscard(writer io.Writer) io.Writer {
+	if writer == nil {
+		return io.Discard
+	}
+	return writer
+}
+
+// RenderOptions applies opts on top of the zero options
+func RenderOptions(opts []Opt) Options {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
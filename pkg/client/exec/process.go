@@ -0,0 +1,37 @@
+package exec
+
+import (
+	"context"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+)
+
+// Result of a command run to completion
+type Result struct {
+	ExitCode int
+	// Stdout and Stderr hold the captured output, they are empty if the output was streamed with WithOutput
+	Stdout []byte
+	Stderr []byte
+}
+
+// Process is a running exec, its output is read from Messages and its stdin written with Write
+type Process struct {
+	*stream.Duplex
+	ID   string
+	wait func(ctx context.Context) (int, error)
+}
+
+func NewProcess(id string, duplex *stream.Duplex, wait func(ctx context.Context) (int, error)) *Process {
+	return &Process{Duplex: duplex, ID: id, wait: wait}
+}
+
+// Wait waits for the output to end and returns the exit code of the process.
+// Messages must be drained concurrently, otherwise the output never ends.
+func (p *Process) Wait(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-p.Done():
+	}
+	return p.wait(ctx)
+}
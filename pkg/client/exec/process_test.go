@@ -0,0 +1,55 @@
+package exec
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestProcessWait(t *testing.T) {
+	client, server := net.Pipe()
+	duplex := stream.NewDuplexStream(context.Background(), client, client, false, stream.Control{}, zap.NewNop().Sugar())
+	process := NewProcess("exec", duplex, func(context.Context) (int, error) {
+		return 3, nil
+	})
+
+	go func() {
+		_, _ = server.Write([]byte("output"))
+		_ = server.Close()
+	}()
+	var output []byte
+	for msg := range process.Messages() {
+		output = append(output, msg.Content...)
+	}
+	require.Equal(t, "output", string(output))
+
+	exitCode, err := process.Wait(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, exitCode)
+}
+
+func TestProcessWaitCancelled(t *testing.T) {
+	client, server := net.Pipe()
+	defer func() { _ = server.Close() }()
+	duplex := stream.NewDuplexStream(context.Background(), client, client, false, stream.Control{}, zap.NewNop().Sugar())
+	defer func() { _ = duplex.Close() }()
+	process := NewProcess("exec", duplex, func(context.Context) (int, error) {
+		return 0, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := process.Wait(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWithOutputDiscardsNil(t *testing.T) {
+	options := RenderOptions([]Opt{WithOutput(nil, nil), WithEnv("A=1"), WithEnv("B=2")})
+	require.NotNil(t, options.Stdout)
+	require.NotNil(t, options.Stderr)
+	require.Equal(t, []string{"A=1", "B=2"}, options.Env)
+}
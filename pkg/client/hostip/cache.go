@@ -0,0 +1,106 @@
+package hostip
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DiscoverTimeout limits a discovery, it is not bound to the caller which started it
+const DiscoverTimeout = 2 * time.Minute
+
+// Cache holds the discovered host IPs per network of one engine
+type Cache struct {
+	mutex sync.RWMutex
+	// daemonHost is the engine the entries were discovered on
+	daemonHost string
+	// entries are keyed by the network name or ID they were requested with, "" for the default network
+	entries map[string]*Result
+	group   singleflight.Group
+}
+
+func NewCache() *Cache {
+	return &Cache{entries: map[string]*Result{}}
+}
+
+// Get returns the cached result for network, or discovers it once for concurrent callers.
+// The discovery is shared, so it isn't cancelled with ctx, but limited by DiscoverTimeout. Callers stop waiting for it
+// once their ctx is done. All entries are dropped if daemonHost differs from the engine the entries were discovered on.
+func (c *Cache) Get(
+	ctx context.Context, daemonHost, network string, discover func(ctx context.Context) (*Result, error),
+) (*Result, error) {
+	c.mutex.RLock()
+	result, ok := c.entries[network]
+	sameHost := c.daemonHost == daemonHost
+	c.mutex.RUnlock()
+	if ok && sameHost {
+		return result, nil
+	}
+	if !sameHost {
+		c.mutex.Lock()
+		if c.daemonHost != daemonHost {
+			c.daemonHost = daemonHost
+			c.entries = map[string]*Result{}
+		}
+		c.mutex.Unlock()
+	}
+
+	ch := c.group.DoChan(daemonHost+"\x00"+network, func() (any, error) {
+		discoverCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DiscoverTimeout)
+		defer cancel()
+		result, err := discover(discoverCtx)
+		if err != nil {
+			return nil, err
+		}
+		c.mutex.Lock()
+		if c.daemonHost == daemonHost {
+			c.entries[network] = result
+		}
+		c.mutex.Unlock()
+		return result, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case shared := <-ch:
+		if shared.Err != nil {
+			return nil, shared.Err
+		}
+		return shared.Val.(*Result), nil
+	}
+}
+
+// Invalidate drops the entries of a network, given by its ID and name. Entries requested with a short ID match too.
+func (c *Cache) Invalidate(id, name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.entries {
+		if key != "" && (key == name || strings.HasPrefix(id, key)) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// InvalidateKey drops the entry requested with network, "" for the default network
+func (c *Cache) InvalidateKey(network string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, network)
+}
+
+// Clear drops all entries
+func (c *Cache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = map[string]*Result{}
+}
+
+// Len returns the number of cached entries
+func (c *Cache) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.entries)
+}
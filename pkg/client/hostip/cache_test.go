@@ -0,0 +1,79 @@
+package hostip
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachePerNetwork(t *testing.T) {
+	cache := NewCache()
+	var calls atomic.Int32
+	discover := func(ip string) func(context.Context) (*Result, error) {
+		return func(context.Context) (*Result, error) {
+			calls.Add(1)
+			return &Result{IP: net.ParseIP(ip), Method: MethodGateway}, nil
+		}
+	}
+
+	result, err := cache.Get(context.Background(), "unix:///docker.sock", "", discover("172.17.0.1"))
+	require.NoError(t, err)
+	require.Equal(t, "172.17.0.1", result.IP.String())
+	result, err = cache.Get(context.Background(), "unix:///docker.sock", "custom", discover("172.18.0.1"))
+	require.NoError(t, err)
+	require.Equal(t, "172.18.0.1", result.IP.String(), "networks must not share an entry")
+	result, err = cache.Get(context.Background(), "unix:///docker.sock", "", discover("10.0.0.1"))
+	require.NoError(t, err)
+	require.Equal(t, "172.17.0.1", result.IP.String())
+	require.Equal(t, int32(2), calls.Load())
+
+	cache.Invalidate("0123456789abcdef", "custom")
+	require.Equal(t, 1, cache.Len())
+	result, err = cache.Get(context.Background(), "unix:///docker.sock", "custom", discover("172.19.0.1"))
+	require.NoError(t, err)
+	require.Equal(t, "172.19.0.1", result.IP.String())
+
+	// a short ID matches the full ID of the removal
+	_, _ = cache.Get(context.Background(), "unix:///docker.sock", "0123456789ab", discover("172.20.0.1"))
+	cache.Invalidate("0123456789abcdef", "other")
+	require.Equal(t, 2, cache.Len())
+
+	_, err = cache.Get(context.Background(), "tcp://remote:2376", "", discover("192.168.0.1"))
+	require.NoError(t, err)
+	require.Equal(t, 1, cache.Len(), "entries of another engine must be dropped")
+}
+
+func TestCacheDiscoveryOutlivesCaller(t *testing.T) {
+	cache := NewCache()
+	started, release := make(chan struct{}), make(chan struct{})
+	discover := func(ctx context.Context) (*Result, error) {
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &Result{IP: net.ParseIP("172.17.0.1"), Method: MethodGateway}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := cache.Get(ctx, "unix:///docker.sock", "", discover)
+		firstErr <- err
+	}()
+	<-started
+	cancel()
+	require.ErrorIs(t, <-firstErr, context.Canceled)
+
+	second := make(chan *Result)
+	go func() {
+		result, err := cache.Get(context.Background(), "unix:///docker.sock", "", discover)
+		require.NoError(t, err)
+		second <- result
+	}()
+	close(release)
+	require.Equal(t, "172.17.0.1", (<-second).IP.String())
+}
@@ -0,0 +1,77 @@
+package hostip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"go.uber.org/zap"
+)
+
+// Tier is one way of discovering the host IP
+type Tier struct {
+	Method Method
+	// Discover returns nil if the method found no address
+	Discover func(ctx context.Context) (net.IP, error)
+}
+
+// Discoverers implement the methods Tiers chooses from
+type Discoverers struct {
+	Gateway    func(ctx context.Context) (net.IP, error)
+	PodmanInfo func(ctx context.Context) (net.IP, error)
+	Hostname   func(ctx context.Context) (net.IP, error)
+	Probe      func(ctx context.Context) (net.IP, error)
+}
+
+// Hostname returns the name containers of engine reach the host with, "" if the engine has none. Only Docker Desktop
+// resolves host.docker.internal, Podman adds host.containers.internal to every container.
+func Hostname(engine *capabilities.Capabilities) string {
+	switch {
+	case engine.IsPodman():
+		return PodmanHostname
+	case engine.Desktop():
+		return DockerHostname
+	}
+	return ""
+}
+
+// Tiers returns the methods which apply to engine, in the order they are tried. None of them pulls an image, except
+// for the probe, which is the last resort.
+func Tiers(engine *capabilities.Capabilities, discoverers Discoverers) []Tier {
+	var tiers []Tier
+	// rootless and desktop engines run in a namespace or VM, their bridge gateways are not the caller's host
+	if !engine.Rootless && !engine.Desktop() {
+		tiers = append(tiers, Tier{Method: MethodGateway, Discover: discoverers.Gateway})
+	}
+	if engine.IsPodman() && engine.Rootless {
+		tiers = append(tiers, Tier{Method: MethodPodmanInfo, Discover: discoverers.PodmanInfo})
+	}
+	if Hostname(engine) != "" {
+		tiers = append(tiers, Tier{Method: MethodHostname, Discover: discoverers.Hostname})
+	}
+	return append(tiers, Tier{Method: MethodProbe, Discover: discoverers.Probe})
+}
+
+// Discover returns the address of the first tier which finds one. Failing tiers are logged and skipped, their errors
+// are returned if no tier finds an address.
+func Discover(ctx context.Context, tiers []Tier, logger *zap.SugaredLogger) (*Result, error) {
+	var errs []error
+	for _, tier := range tiers {
+		ip, err := tier.Discover(ctx)
+		if err != nil {
+			logger.Debugf("host IP discovery via %s failed: %v", tier.Method, err)
+			errs = append(errs, fmt.Errorf("%s: %w", tier.Method, err))
+			continue
+		}
+		if ip != nil {
+			logger.Debugf("discovered host IP %s via %s", ip, tier.Method)
+			return &Result{IP: ip, Method: tier.Method}, nil
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no method found the host IP")
+	}
+	return nil, fmt.Errorf("failed to discover the host IP: %w", errors.Join(errs...))
+}
@@ -0,0 +1,83 @@
+package hostip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTiers(t *testing.T) {
+	methods := func(engine *capabilities.Capabilities) []Method {
+		var result []Method
+		for _, tier := range Tiers(engine, Discoverers{}) {
+			result = append(result, tier.Method)
+		}
+		return result
+	}
+
+	require.Equal(t, []Method{MethodGateway, MethodProbe}, methods(&capabilities.Capabilities{
+		Engine: capabilities.EngineDocker,
+	}))
+	require.Equal(t, []Method{MethodProbe}, methods(&capabilities.Capabilities{
+		Engine: capabilities.EngineDocker, Rootless: true,
+	}), "rootless gateways are not the host")
+	require.Equal(t, []Method{MethodHostname, MethodProbe}, methods(&capabilities.Capabilities{
+		Engine: capabilities.EngineDocker, OperatingSystem: "Docker Desktop",
+	}), "desktop gateways are in the VM")
+	require.Equal(t, []Method{MethodGateway, MethodHostname, MethodProbe}, methods(&capabilities.Capabilities{
+		Engine: capabilities.EnginePodman,
+	}))
+	require.Equal(t, []Method{MethodPodmanInfo, MethodHostname, MethodProbe}, methods(&capabilities.Capabilities{
+		Engine: capabilities.EnginePodman, Rootless: true,
+	}))
+}
+
+func TestHostname(t *testing.T) {
+	require.Equal(t, DockerHostname, Hostname(&capabilities.Capabilities{
+		Engine: capabilities.EngineDocker, OperatingSystem: "Docker Desktop",
+	}))
+	require.Equal(t, PodmanHostname, Hostname(&capabilities.Capabilities{Engine: capabilities.EnginePodman}))
+	require.Empty(t, Hostname(&capabilities.Capabilities{Engine: capabilities.EngineDocker}),
+		"only desktop engines resolve host.docker.internal")
+}
+
+func TestDiscover(t *testing.T) {
+	var tried []Method
+	tier := func(method Method, ip string, err error) Tier {
+		return Tier{Method: method, Discover: func(context.Context) (net.IP, error) {
+			tried = append(tried, method)
+			return net.ParseIP(ip), err
+		}}
+	}
+	logger := zap.NewNop().Sugar()
+
+	result, err := Discover(context.Background(), []Tier{
+		tier(MethodGateway, "", errors.New("no such network")),
+		tier(MethodPodmanInfo, "", nil),
+		tier(MethodProbe, "10.0.2.2", nil),
+	}, logger)
+	require.NoError(t, err)
+	require.Equal(t, &Result{IP: net.ParseIP("10.0.2.2"), Method: MethodProbe}, result)
+	require.Equal(t, []Method{MethodGateway, MethodPodmanInfo, MethodProbe}, tried)
+
+	tried = nil
+	result, err = Discover(context.Background(), []Tier{
+		tier(MethodGateway, "172.17.0.1", nil),
+		tier(MethodProbe, "10.0.2.2", nil),
+	}, logger)
+	require.NoError(t, err)
+	require.Equal(t, MethodGateway, result.Method)
+	require.Equal(t, []Method{MethodGateway}, tried, "later tiers must not run")
+
+	_, err = Discover(context.Background(), []Tier{
+		tier(MethodGateway, "", errors.New("no such network")),
+		tier(MethodProbe, "", errors.New("pull failed")),
+	}, logger)
+	require.ErrorContains(t, err, "no such network")
+	require.ErrorContains(t, err, "pull failed")
+}
@@ -0,0 +1,65 @@
+package hostip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/blang/semver/v4"
+)
+
+// Method is the way the host IP was discovered
+type Method string
+
+const (
+	// MethodGateway uses the IPAM gateway of the network, the host's address on rootful bridge networks
+	MethodGateway Method = "network-gateway"
+	// MethodHostname resolves host.docker.internal or host.containers.internal in the engine's network namespace,
+	// with a helper image which is present locally
+	MethodHostname Method = "hostname"
+	// MethodPodmanInfo derives the address from the rootless network command (slirp4netns or pasta)
+	MethodPodmanInfo Method = "podman-info"
+	// MethodProbe starts a helper container and asks it for the host's address
+	MethodProbe Method = "container-probe"
+)
+
+const (
+	// DockerHostname resolves to the host in containers of Docker Desktop
+	DockerHostname = "host.docker.internal"
+	// PodmanHostname is added to the hosts file of Podman containers
+	PodmanHostname = "host.containers.internal"
+	// SlirpHostIP is the address slirp4netns maps to the host's loopback
+	SlirpHostIP = "10.0.2.2"
+	// PastaHostIP is the address pasta maps to the host since Podman 5.3 (--map-guest-addr)
+	PastaHostIP = "169.254.1.2"
+)
+
+// Result is a discovered host IP
+type Result struct {
+	IP     net.IP
+	Method Method
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%s (%s)", r.IP.String(), r.Method)
+}
+
+// pastaMapGuestAddr is the first Podman version mapping PastaHostIP to the host
+var pastaMapGuestAddr = semver.MustParse("5.3.0")
+
+// RootlessHostIP returns the host address of rootless Podman containers for the rootless network command
+// (slirp4netns or pasta), nil if there is no fixed address
+func RootlessHostIP(networkCmd string, podmanVersion string) (net.IP, error) {
+	switch networkCmd {
+	case "slirp4netns":
+		return net.ParseIP(SlirpHostIP), nil
+	case "pasta":
+		version, err := semver.ParseTolerant(podmanVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid podman version %s: %w", podmanVersion, err)
+		}
+		if version.GE(pastaMapGuestAddr) {
+			return net.ParseIP(PastaHostIP), nil
+		}
+	}
+	return nil, nil
+}
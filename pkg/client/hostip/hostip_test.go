@@ -0,0 +1,29 @@
+package hostip
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootlessHostIP(t *testing.T) {
+	for _, tc := range []struct {
+		cmd      string
+		version  string
+		expected net.IP
+	}{
+		{"slirp4netns", "4.9.3", net.ParseIP(SlirpHostIP)},
+		{"pasta", "5.3.1", net.ParseIP(PastaHostIP)},
+		{"pasta", "5.6.0-dev", net.ParseIP(PastaHostIP)},
+		{"pasta", "5.2.5", nil},
+		{"", "5.4.0", nil},
+	} {
+		ip, err := RootlessHostIP(tc.cmd, tc.version)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, ip, "%s %s", tc.cmd, tc.version)
+	}
+
+	_, err := RootlessHostIP("pasta", "invalid")
+	require.Error(t, err)
+}
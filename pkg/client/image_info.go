@@ -5,61 +5,128 @@ import (
 	"fmt"
 
 	"github.com/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/inspect"
 )
 
 func (c *Client) ImageGetManifest(ctx context.Context, ref reference.Named, platform *v1.Platform) (
 	v1.Hash, *v1.Manifest, error,
+) {
+	ref, err := c.rewriteRef(ref)
+	if err != nil {
+		return v1.Hash{}, nil, err
+	}
+	return c.imageGetManifest(ctx, ref, platform)
+}
+
+func (c *Client) imageGetManifest(ctx context.Context, ref reference.Named, platform *v1.Platform) (
+	v1.Hash, *v1.Manifest, error,
+) {
+	resolved, manifest, err := c.imageResolve(ctx, ref, ref, platform)
+	if err != nil {
+		return v1.Hash{}, nil, err
+	}
+	return resolved.ImageID, manifest, nil
+}
+
+// ImageResolve pins ref to the digest it currently points to in its registry. The canonical reference keeps the name
+// of ref, even if it is pulled from a mirror.
+func (c *Client) ImageResolve(ctx context.Context, ref reference.Named, platform *v1.Platform) (
+	*inspect.Resolved, error,
+) {
+	mirrored, err := c.rewriteRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	resolved, _, err := c.imageResolve(ctx, ref, mirrored, platform)
+	return resolved, err
+}
+
+func (c *Client) imageResolve(ctx context.Context, ref, mirrored reference.Named, requested *v1.Platform) (
+	*inspect.Resolved, *v1.Manifest, error,
 ) {
 	var err error
-	if platform == nil {
-		platform, err = c.SystemDefaultPlatform(ctx)
+	if requested == nil {
+		requested, err = c.SystemDefaultPlatform(ctx)
 		if err != nil {
-			return v1.Hash{}, nil, err
+			return nil, nil, err
 		}
 	}
-	opts := []remote.Option{
-		remote.WithAuthFromKeychain(c.authProvider),
-		remote.WithContext(ctx),
-		remote.WithPlatform(*platform),
-	}
 
-	nameRef, err := name.ParseReference(ref.String())
+	nameRef, err := name.ParseReference(mirrored.String())
 	if err != nil {
-		return v1.Hash{}, nil, err
+		return nil, nil, err
 	}
 
-	desc, err := remote.Get(nameRef, opts...)
+	desc, err := remote.Get(nameRef, c.remoteOptions(ctx)...)
 	if err != nil {
-		return v1.Hash{}, nil, fmt.Errorf("failed to get descriptor: %w", err)
+		return nil, nil, fmt.Errorf("failed to get descriptor: %w", err)
 	}
 
-	img, err := desc.Image()
+	img, selected, err := inspect.SelectImage(ref, desc, *requested)
 	if err != nil {
-		return v1.Hash{}, nil, fmt.Errorf("failed to get image: %w", err)
+		return nil, nil, err
 	}
-	isPodman, err := c.SystemIsPodman(ctx)
+	caps, err := c.SystemCapabilities(ctx)
 	if err != nil {
-		return v1.Hash{}, nil, fmt.Errorf("failed to determine if runtime is podman: %w", err)
+		return nil, nil, fmt.Errorf("failed to determine the image store: %w", err)
 	}
 	manifest, err := img.Manifest()
 	if err != nil {
-		return v1.Hash{}, nil, err
+		return nil, nil, err
+	}
+	manifestDigest, err := img.Digest()
+	if err != nil {
+		return nil, nil, err
+	}
+	configDigest, err := img.ConfigName()
+	if err != nil {
+		return nil, nil, err
+	}
+	canonical, err := reference.WithDigest(reference.TrimNamed(ref), digest.Digest(desc.Digest.String()))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var id v1.Hash
-	if isPodman {
-		// Podman uses config digest as image-id
-		id, err = img.ConfigName()
-	} else {
-		// Docker uses manifest/index digest as image-id
-		id = desc.Digest
+	resolved := &inspect.Resolved{
+		Canonical:      canonical,
+		Digest:         desc.Digest,
+		ManifestDigest: manifestDigest,
+		ConfigDigest:   configDigest,
+		Platform:       selected,
+		IDs: inspect.IDs{
+			Classic:    configDigest,
+			Containerd: desc.Digest,
+			Podman:     configDigest,
+		},
 	}
+	resolved.ImageID = resolved.IDs.For(caps.ImageStore())
+	return resolved, manifest, nil
+}
+
+// ImageInspectRemote returns the descriptor tree of ref from its registry, without pulling it
+func (c *Client) ImageInspectRemote(ctx context.Context, ref reference.Named, opts ...inspect.Opt) (
+	*inspect.Image, error,
+) {
+	ref, err := c.rewriteRef(ref)
 	if err != nil {
-		return v1.Hash{}, nil, err
+		return nil, err
 	}
+	return inspect.Remote(ref, c.remoteOptions(ctx), opts...)
+}
 
-	return id, manifest, nil
+// remoteOptions are the options for registry access, using the auth provider as keychain
+func (c *Client) remoteOptions(ctx context.Context) []remote.Option {
+	var keychain authn.Keychain = authn.NewMultiKeychain()
+	if c.authProvider != nil {
+		keychain = c.authProvider
+	}
+	return []remote.Option{
+		remote.WithAuthFromKeychain(keychain),
+		remote.WithContext(ctx),
+	}
 }
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/distribution/reference"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+)
+
+// rewriteRef applies the configured mirror rules
+func (c *Client) rewriteRef(ref reference.Named) (reference.Named, error) {
+	rewritten, err := c.rewriter.Rewrite(ref)
+	if err != nil {
+		return nil, err
+	}
+	if rewritten.String() != ref.String() {
+		c.logger.Debugf("using mirror %s for %s", rewritten.String(), ref.String())
+	}
+	return rewritten, nil
+}
+
+// tagPulled tags the pulled image with the references the caller asked for once the pull completed: the mirrored
+// reference if the image was pulled by digest, and the original one if it was pulled from a mirror. This way callers
+// can keep using the reference they asked for. A failed tag replaces the final event with an error, images of pulls
+// which reported an error are not tagged.
+// Digest references can't be tagged, they stay known by the mirror's name, see localImageForPolicy.
+func (c *Client) tagPulled(
+	ctx context.Context, pulled, mirrored, original reference.Named, ch chan events.PullEvent,
+) chan events.PullEvent {
+	var targets []reference.Named
+	if _, canonical := mirrored.(reference.Canonical); !canonical && mirrored.String() != pulled.String() {
+		// the engine pulled the verified digest, the tag has to be set like a pull of it would
+		targets = append(targets, reference.TagNameOnly(mirrored))
+	}
+	if _, tagged := original.(reference.Tagged); tagged && original.String() != mirrored.String() {
+		targets = append(targets, original)
+	}
+	if len(targets) == 0 {
+		return ch
+	}
+
+	out := make(chan events.PullEvent)
+	go func() {
+		defer close(out)
+		// the engine reports the final status even after a rejected digest, the rejected image must not be tagged
+		failed := false
+		for event := range ch {
+			switch event.(type) {
+			case *events.PullError, *events.LayerError:
+				failed = true
+			case *events.Retrying:
+				failed = false
+			}
+			if _, ok := event.(events.FinalEvent); ok && !failed {
+				for _, target := range targets {
+					if err := c.ImageTag(ctx, pulled.String(), target.String()); err != nil {
+						event = &events.PullError{
+							Error: fmt.Sprintf("failed to tag %s as %s: %v", pulled.String(), target.String(), err),
+						}
+						break
+					}
+				}
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -2,28 +2,54 @@ package client
 
 import (
 	"context"
+	"fmt"
+	"io"
 
 	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/registry"
 	"github.com/google/go-containerregistry/pkg/v1"
 	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/inspect"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/platform"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/state"
 	"go.uber.org/zap"
 )
 
-func (c *Client) ImagePullWithEvents(ctx context.Context, ref reference.Named, options image.PullOptions) (
+// ImagePullWithEvents pulls ref and returns the image id, the manifest and the pull event stream.
+// If the pull policy allows using a local image, the id of the local image, a nil manifest and a stream containing
+// a single *events.Skipped are returned.
+func (c *Client) ImagePullWithEvents(
+	ctx context.Context, ref reference.Named, options image.PullOptions, opts ...pull.Opt,
+) (
 	v1.Hash, *v1.Manifest, chan events.PullEvent, error,
 ) {
+	pullOpts := pull.RenderOptions(c.pullDefaults, opts)
+	original := ref
+	ref, err := c.rewriteRef(ref)
+	if err != nil {
+		return v1.Hash{}, nil, nil, err
+	}
+	local, err := c.localImageForPolicy(ctx, original, ref, pullOpts.Policy)
+	if err != nil {
+		return v1.Hash{}, nil, nil, err
+	} else if local != nil {
+		if pullOpts.Verifier != nil {
+			if err := c.verifyLocal(ctx, pullOpts.Verifier, *local, original, ref); err != nil {
+				return v1.Hash{}, nil, nil, err
+			}
+		}
+		id, eventChan, err := skippedPull(local, original, ref, pullOpts.Policy)
+		return id, nil, eventChan, err
+	}
 	if options.RegistryAuth != "" || options.PrivilegeFunc != nil {
 		c.logger.WithOptions(zap.AddStacktrace(zap.DPanicLevel)).Warnf("privilege function and registry auth in options are not supported, please use auth provider instead")
 		options.RegistryAuth = ""
 		options.PrivilegeFunc = nil
 	}
 	var encodedAuth string
-	var err error
 	if c.authProvider != nil {
 		c.logger.Debugf("using configured auth provider")
 		encodedAuth, err = registry.EncodeAuthConfig(c.authProvider.AuthConfig(ref))
@@ -33,59 +59,129 @@ func (c *Client) ImagePullWithEvents(ctx context.Context, ref reference.Named, o
 	}
 	options.RegistryAuth = encodedAuth
 
-	imageId, manifest, err := c.getManifest(ctx, ref, options)
+	resolved, manifest, err := c.getManifest(ctx, ref, options)
 	if err != nil {
 		return v1.Hash{}, nil, nil, err
 	}
+	if resolved.Platform != nil {
+		if options.Platform != "" {
+			// the engine pulls the variant fallback that was resolved
+			options.Platform = platform.Format(*resolved.Platform)
+		}
+		c.warnEmulation(ctx, ref, *resolved.Platform)
+	}
+	// the engine has to pull what was resolved (and verified), unless the tag may move in between
+	_, pinned := ref.(reference.Canonical)
+	if pullOpts.Verifier != nil {
+		c.logger.Debugf("verifying %s@%s", ref.String(), resolved.Digest.String())
+		if err := pullOpts.Verifier.Verify(ref, resolved.Digest, c.remoteOptions(ctx)); err != nil {
+			return v1.Hash{}, nil, nil, err
+		}
+		pinned = true
+	}
+	// pulling by digest keeps a moved tag from replacing the verified image
+	pullRef := ref
+	if pullOpts.Verifier != nil {
+		pullRef, err = reference.WithDigest(reference.TrimNamed(ref), digest.Digest(resolved.Digest.String()))
+		if err != nil {
+			return v1.Hash{}, nil, nil, err
+		}
+	}
 
-	reader, err := c.ImagePull(ctx, ref.String(), options)
+	caps, err := c.SystemCapabilities(ctx)
 	if err != nil {
 		return v1.Hash{}, nil, nil, err
 	}
-
-	return imageId, manifest, pull.ParseStream(ctx, reader), nil
+	var eventChan chan events.PullEvent
+	reader, err := c.ImagePull(ctx, pullRef.String(), options)
+	if pullOpts.Retry == nil || !pullOpts.Retry.Enabled() {
+		if err != nil {
+			return v1.Hash{}, nil, nil, err
+		}
+		eventChan = pull.ParseStream(ctx, reader)
+	} else {
+		if err != nil && !pullOpts.Retry.ShouldRetry(1, err) {
+			return v1.Hash{}, nil, nil, err
+		}
+		open := func(ctx context.Context) (io.ReadCloser, error) {
+			return c.ImagePull(ctx, pullRef.String(), options)
+		}
+		eventChan = pull.ParseStreamWithRetry(ctx, *pullOpts.Retry, caps.ImageStore(), reader, err, open)
+	}
+	if pinned {
+		// a safety net, the engine already pulls by digest if the image was verified
+		eventChan = pull.CheckDigest(ctx, resolved, eventChan)
+	}
+	return resolved.ImageID, manifest, c.tagPulled(ctx, pullRef, ref, original, eventChan), nil
 }
 
-func (c *Client) ImagePullWithState(ctx context.Context, ref reference.Named, options image.PullOptions) (
+func (c *Client) ImagePullWithState(
+	ctx context.Context, ref reference.Named, options image.PullOptions, opts ...pull.Opt,
+) (
 	v1.Hash, *v1.Manifest, chan state.Pull, error,
 ) {
-	isPodman, err := c.SystemIsPodman(ctx)
+	caps, err := c.SystemCapabilities(ctx)
 	if err != nil {
 		return v1.Hash{}, nil, nil, err
 	}
 
-	id, manifest, eventChan, err := c.ImagePullWithEvents(ctx, ref, options)
+	id, manifest, eventChan, err := c.ImagePullWithEvents(ctx, ref, options, opts...)
 	if err != nil {
 		return v1.Hash{}, nil, nil, err
 	}
 
-	return id, manifest, pull.StateFromStream(ctx, ref, isPodman, eventChan, manifest, id), nil
+	return id, manifest, pull.StateFromStream(ctx, ref, caps.ImageStore(), eventChan, manifest, id, c.logger), nil
 }
 
-func (c *Client) ImagePullSimple(ctx context.Context, ref reference.Named, options image.PullOptions) (
+func (c *Client) ImagePullSimple(
+	ctx context.Context, ref reference.Named, options image.PullOptions, opts ...pull.Opt,
+) (
 	digest.Digest, error,
 ) {
-	dig, _, eventChan, err := c.ImagePullWithEvents(ctx, ref, options)
+	dig, _, eventChan, err := c.ImagePullWithEvents(ctx, ref, options, opts...)
 	if err != nil {
 		return "", err
 	}
 
-	for range eventChan {
+	if err := pull.Wait(eventChan); err != nil {
+		return "", fmt.Errorf("failed to pull %s: %w", ref.String(), err)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
 	}
 
 	return digest.Digest(dig.String()), nil
 }
 
 func (c *Client) getManifest(ctx context.Context, ref reference.Named, options image.PullOptions) (
-	v1.Hash, *v1.Manifest, error,
+	*inspect.Resolved, *v1.Manifest, error,
 ) {
 	var platform *v1.Platform
 	var err error
 	if options.Platform != "" {
 		platform, err = v1.ParsePlatform(options.Platform)
 		if err != nil {
-			return v1.Hash{}, nil, err
+			return nil, nil, err
 		}
 	}
-	return c.ImageGetManifest(ctx, ref, platform)
+	return c.imageResolve(ctx, ref, ref, platform)
+}
+
+// warnEmulation warns if containers of the pulled platform won't run natively on the engine
+func (c *Client) warnEmulation(ctx context.Context, ref reference.Named, p v1.Platform) {
+	caps, err := c.SystemCapabilities(ctx)
+	if err != nil || !caps.NeedsEmulation(p) {
+		return
+	}
+	if caps.CanRun(p) {
+		c.logger.Warnf(
+			"pulling %s for %s, containers of it run emulated on %s",
+			ref.String(), platform.Format(p), platform.Format(caps.DefaultPlatform),
+		)
+	} else {
+		c.logger.Warnf(
+			"pulling %s for %s, which needs emulation on %s, but no emulator for it was detected",
+			ref.String(), platform.Format(p), platform.Format(caps.DefaultPlatform),
+		)
+	}
 }
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/state"
+)
+
+// ImagePullMany pulls several images with at most concurrency pulls running at once (unbounded if <= 0).
+// The state channel must be drained, the result channel receives the per-image results in request order
+// once all pulls finished.
+func (c *Client) ImagePullMany(ctx context.Context, requests []pull.Request, concurrency int) (
+	chan *state.Multi, chan []pull.Result, error,
+) {
+	refs := make([]reference.Named, len(requests))
+	for i, req := range requests {
+		if req.Ref == nil {
+			return nil, nil, fmt.Errorf("request %d has no reference", i)
+		}
+		refs[i] = req.Ref
+	}
+	if concurrency <= 0 || concurrency > len(requests) {
+		concurrency = max(len(requests), 1)
+	}
+
+	states := make(chan *state.Multi)
+	results := make(chan []pull.Result, 1)
+	go c.pullMany(ctx, requests, refs, concurrency, states, results)
+	return states, results, nil
+}
+
+type pullUpdate struct {
+	index int
+	state state.Pull
+}
+
+func (c *Client) pullMany(
+	ctx context.Context, requests []pull.Request, refs []reference.Named, concurrency int,
+	states chan *state.Multi, results chan []pull.Result,
+) {
+	defer close(results)
+	defer close(states)
+
+	updates := make(chan pullUpdate)
+	output := make([]pull.Result, len(requests))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				output[i] = pull.Result{Ref: req.Ref, Err: ctx.Err()}
+				return
+			}
+			output[i] = c.pullOne(ctx, i, req, updates)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+
+	current := state.NewMultiState(refs)
+	for update := range updates {
+		current = current.With(update.index, update.state)
+		select {
+		case states <- current:
+		case <-ctx.Done():
+		}
+	}
+	results <- output
+}
+
+func (c *Client) pullOne(ctx context.Context, index int, req pull.Request, updates chan pullUpdate) pull.Result {
+	result := pull.Result{Ref: req.Ref}
+	options := image.PullOptions{}
+	if req.Platform != nil {
+		options.Platform = req.Platform.String()
+	}
+
+	id, _, stateChan, err := c.ImagePullWithState(ctx, req.Ref, options, req.Opts...)
+	if err != nil {
+		result.Err = err
+		// the pull failed before streaming, without a state it would be reported as queued
+		if errored, stateErr := state.NewPullState(
+			req.Ref, "", nil, v1.Hash{}, &events.PullError{Error: err.Error()},
+		); stateErr == nil {
+			updates <- pullUpdate{index, errored}
+		}
+		return result
+	}
+	result.ImageID = id
+
+	var last state.Pull
+	for current := range stateChan {
+		last = current
+		updates <- pullUpdate{index, current}
+	}
+	switch last := last.(type) {
+	case *state.PullComplete:
+		result.Digest = last.ImageDigest
+		result.DownloadedNewer = last.DownloadedNewer
+		if last.ImageDigest != "" {
+			result.Canonical, _ = reference.WithDigest(reference.TrimNamed(req.Ref), last.ImageDigest)
+		}
+	case *state.PullErrored:
+		result.Err = fmt.Errorf("failed to pull %s: %s", req.Ref.String(), last.Message())
+	default:
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+		} else {
+			result.Err = fmt.Errorf("pull of %s did not complete", req.Ref.String())
+		}
+	}
+	return result
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+	"github.com/silenium-dev/docker-wrapper/pkg/errors"
+)
+
+// localImageForPolicy returns the local image if the pull policy allows skipping the pull of ref.
+// mirrored is the reference the remote digest is looked up with.
+func (c *Client) localImageForPolicy(
+	ctx context.Context, ref, mirrored reference.Named, policy pull.Policy,
+) (*image.InspectResponse, error) {
+	return c.policyImages().LocalImage(ctx, ref, mirrored, policy)
+}
+
+func (c *Client) policyImages() pull.Images {
+	return pull.Images{
+		Inspect: func(ctx context.Context, ref reference.Named) (image.InspectResponse, error) {
+			return c.ImageInspect(ctx, ref.String())
+		},
+		Head: func(ctx context.Context, ref reference.Named) (v1.Hash, error) {
+			nameRef, err := name.ParseReference(ref.String())
+			if err != nil {
+				return v1.Hash{}, err
+			}
+			desc, err := remote.Head(nameRef, c.remoteOptions(ctx)...)
+			if err != nil {
+				return v1.Hash{}, err
+			}
+			return desc.Digest, nil
+		},
+		Logger: c.logger,
+	}
+}
+
+// verifyLocal verifies a local image used instead of pulling by its repo digests. Images without a repo digest in
+// the repository of ref or mirrored, e.g. locally built or tagged ones, can't be verified and are rejected.
+func (c *Client) verifyLocal(
+	ctx context.Context, verifier pull.Verifier, local image.InspectResponse, ref, mirrored reference.Named,
+) error {
+	digests := pull.RepoDigests(local, ref, mirrored)
+	if len(digests) == 0 {
+		return &errors.VerificationError{
+			Ref: ref.String(), Digest: local.ID, Reason: "the local image has no repo digest to verify",
+		}
+	}
+	var err error
+	for _, dig := range digests {
+		var hash v1.Hash
+		hash, err = v1.NewHash(dig.String())
+		if err != nil {
+			continue
+		}
+		c.logger.Debugf("verifying local image %s@%s", mirrored.String(), dig.String())
+		if err = verifier.Verify(mirrored, hash, c.remoteOptions(ctx)); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// skippedPull returns the pull result for a local image, which was not pulled due to the pull policy.
+// mirrored is the reference the image would have been pulled with.
+func skippedPull(local *image.InspectResponse, ref, mirrored reference.Named, policy pull.Policy) (
+	v1.Hash, chan events.PullEvent, error,
+) {
+	id, err := v1.NewHash(local.ID)
+	if err != nil {
+		return v1.Hash{}, nil, fmt.Errorf("invalid local image id %s: %w", local.ID, err)
+	}
+	var repoDigest digest.Digest
+	if digests := pull.RepoDigests(*local, ref, mirrored); len(digests) > 0 {
+		repoDigest = digests[0]
+	}
+	ch := make(chan events.PullEvent, 1)
+	ch <- &events.Skipped{
+		Digest: repoDigest,
+		Reason: fmt.Sprintf("image present locally (policy %s)", policy.String()),
+	}
+	close(ch)
+	return id, ch, nil
+}
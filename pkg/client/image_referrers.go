@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"io"
+
+	"github.com/distribution/reference"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/inspect"
+)
+
+// ImageReferrers lists signatures, attestations and other artifacts attached to ref (and its platform manifests)
+func (c *Client) ImageReferrers(ctx context.Context, ref reference.Named) ([]inspect.Referrer, error) {
+	ref, err := c.rewriteRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	return inspect.Referrers(ref, c.remoteOptions(ctx))
+}
+
+// ImageFetchReferrerBlob opens a blob of a referrer returned by ImageReferrers
+func (c *Client) ImageFetchReferrerBlob(ctx context.Context, referrer inspect.Referrer, blob v1.Descriptor) (
+	io.ReadCloser, error,
+) {
+	return inspect.FetchBlob(referrer, blob, c.remoteOptions(ctx))
+}
@@ -0,0 +1,139 @@
+package inspect
+
+import (
+	"time"
+
+	"github.com/distribution/reference"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/platform"
+)
+
+// Image is the descriptor tree of a remote image: the index (if any) and all of its image manifests
+type Image struct {
+	Ref reference.Named
+	// Digest is the digest of the manifest or index the reference resolves to
+	Digest    v1.Hash
+	MediaType types.MediaType
+	// Index is nil for single-manifest images
+	Index *v1.IndexManifest
+	// Manifests contains all image manifests of the index in index order, or the single manifest
+	Manifests []Manifest
+}
+
+// Platforms returns the platforms of all runnable (non-attestation) manifests
+func (i *Image) Platforms() []v1.Platform {
+	var result []v1.Platform
+	for _, m := range i.Manifests {
+		if m.Platform != nil && !m.IsAttestation() {
+			result = append(result, *m.Platform)
+		}
+	}
+	return result
+}
+
+// ForPlatform returns the first runnable manifest satisfying platform, nil if there is none
+func (i *Image) ForPlatform(platform v1.Platform) *Manifest {
+	for idx, m := range i.Manifests {
+		if m.IsAttestation() {
+			continue
+		}
+		if m.Platform == nil || m.Platform.Satisfies(platform) {
+			return &i.Manifests[idx]
+		}
+	}
+	return nil
+}
+
+// BestMatch returns the runnable manifest closest to platform, falling back to older variants of its architecture
+// (e.g. arm/v6 for arm/v7). It returns nil if there is none.
+func (i *Image) BestMatch(p v1.Platform) *Manifest {
+	var indices []int
+	var candidates []v1.Platform
+	for idx, m := range i.Manifests {
+		if m.Platform != nil && !m.IsAttestation() {
+			indices = append(indices, idx)
+			candidates = append(candidates, *m.Platform)
+		}
+	}
+	if match := platform.BestMatch(candidates, p); match >= 0 {
+		return &i.Manifests[indices[match]]
+	}
+	return nil
+}
+
+// Manifest is one image manifest with its config and layers
+type Manifest struct {
+	Descriptor v1.Descriptor
+	// Platform is taken from the index entry, or the config for single-manifest images
+	Platform *v1.Platform
+	Manifest *v1.Manifest
+	Config   *v1.ConfigFile
+	Layers   []Layer
+	// CompressedSize is the sum of the layer sizes, i.e. the amount of data to download
+	CompressedSize int64
+	// UncompressedSize is the sum of the uncompressed layer sizes, only set when computed with WithUncompressedSizes
+	UncompressedSize int64
+	IDs              IDs
+}
+
+// attestationAnnotation marks BuildKit attestation manifests in an index
+const attestationAnnotation = "vnd.docker.reference.type"
+
+func (m *Manifest) IsAttestation() bool {
+	return m.Descriptor.Annotations[attestationAnnotation] == "attestation-manifest"
+}
+
+func (m *Manifest) Env() []string {
+	return m.Config.Config.Env
+}
+
+func (m *Manifest) Entrypoint() []string {
+	return m.Config.Config.Entrypoint
+}
+
+func (m *Manifest) Cmd() []string {
+	return m.Config.Config.Cmd
+}
+
+func (m *Manifest) Labels() map[string]string {
+	return m.Config.Config.Labels
+}
+
+func (m *Manifest) Created() time.Time {
+	return m.Config.Created.Time
+}
+
+func (m *Manifest) History() []v1.History {
+	return m.Config.History
+}
+
+type Layer struct {
+	Descriptor v1.Descriptor
+	DiffID     v1.Hash
+	// UncompressedSize is only set when computed with WithUncompressedSizes
+	UncompressedSize int64
+}
+
+// IDs are the local image ids the engines assign after pulling
+type IDs struct {
+	// Classic is the id in docker's graph driver store, the config digest
+	Classic v1.Hash
+	// Containerd is the id in docker's containerd image store, the digest the reference resolves to (manifest or index)
+	Containerd v1.Hash
+	// Podman uses the config digest
+	Podman v1.Hash
+}
+
+// For returns the id an image store assigns
+func (i IDs) For(store capabilities.ImageStore) v1.Hash {
+	switch store {
+	case capabilities.ImageStoreContainerd:
+		return i.Containerd
+	case capabilities.ImageStorePodman:
+		return i.Podman
+	default:
+		return i.Classic
+	}
+}
@@ -0,0 +1,208 @@
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+type ReferrerSource string
+
+const (
+	// SourceReferrersAPI are OCI 1.1 referrers, from the referrers API or its tag schema fallback
+	SourceReferrersAPI ReferrerSource = "referrers-api"
+	// SourceCosignTag are cosign's sha256-<digest>.sig/.att/.sbom tags
+	SourceCosignTag ReferrerSource = "cosign-tag"
+	// SourceAttestationManifest are BuildKit attestation manifests inside an index
+	SourceAttestationManifest ReferrerSource = "attestation-manifest"
+)
+
+// cosignSuffixes are the tag suffixes of cosign signatures, attestations and SBOMs
+var cosignSuffixes = []string{"sig", "att", "sbom"}
+
+const attestationDigestAnnotation = "vnd.docker.reference.digest"
+
+// Referrer is an artifact (signature, SBOM, provenance, ...) attached to an image manifest or index
+type Referrer struct {
+	Source ReferrerSource
+	// Repository the referrer (and its blobs) is stored in
+	Repository name.Repository
+	// Subject is the digest of the manifest or index the referrer refers to
+	Subject    v1.Hash
+	Descriptor v1.Descriptor
+	// ArtifactType is taken from the descriptor, the config descriptor or the config media type, in that order
+	ArtifactType string
+	Annotations  map[string]string
+	Manifest     *v1.Manifest
+	// Tag is set for SourceCosignTag referrers
+	Tag string
+}
+
+// Layers returns the blobs of the referrer, which hold the actual artifact content
+func (r *Referrer) Layers() []v1.Descriptor {
+	if r.Manifest == nil {
+		return nil
+	}
+	return r.Manifest.Layers
+}
+
+// Referrers lists the referrers of ref and, if it is an index, of its image manifests
+func Referrers(ref reference.Named, remoteOpts []remote.Option) ([]Referrer, error) {
+	nameRef, err := name.ParseReference(ref.String())
+	if err != nil {
+		return nil, err
+	}
+	repo := nameRef.Context()
+	desc, err := remote.Get(nameRef, remoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get descriptor: %w", err)
+	}
+
+	subjects := []v1.Hash{desc.Digest}
+	var result []Referrer
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get index: %w", err)
+		}
+		idxManifest, err := idx.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get index manifest: %w", err)
+		}
+		for _, entry := range idxManifest.Manifests {
+			if entry.Annotations[attestationAnnotation] != "attestation-manifest" {
+				if entry.MediaType.IsImage() {
+					subjects = append(subjects, entry.Digest)
+				}
+				continue
+			}
+			subject, err := v1.NewHash(entry.Annotations[attestationDigestAnnotation])
+			if err != nil {
+				subject = desc.Digest
+			}
+			referrer, err := newReferrer(repo, SourceAttestationManifest, subject, entry, remoteOpts)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, *referrer)
+		}
+	}
+
+	for _, subject := range subjects {
+		referrers, err := ReferrersOf(repo, subject, remoteOpts)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, referrers...)
+	}
+	return result, nil
+}
+
+// ReferrersOf lists the OCI 1.1 referrers and cosign tags of a single manifest or index in repo
+func ReferrersOf(repo name.Repository, subject v1.Hash, remoteOpts []remote.Option) ([]Referrer, error) {
+	result, err := apiReferrers(repo, subject, remoteOpts)
+	if err != nil {
+		return nil, err
+	}
+	referrers, err := cosignReferrers(repo, subject, remoteOpts)
+	if err != nil {
+		return nil, err
+	}
+	return append(result, referrers...), nil
+}
+
+func apiReferrers(repo name.Repository, subject v1.Hash, remoteOpts []remote.Option) ([]Referrer, error) {
+	idx, err := remote.Referrers(repo.Digest(subject.String()), remoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list referrers of %s: %w", subject.String(), err)
+	}
+	idxManifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list referrers of %s: %w", subject.String(), err)
+	}
+	var result []Referrer
+	for _, desc := range idxManifest.Manifests {
+		referrer, err := newReferrer(repo, SourceReferrersAPI, subject, desc, remoteOpts)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *referrer)
+	}
+	return result, nil
+}
+
+func cosignReferrers(repo name.Repository, subject v1.Hash, remoteOpts []remote.Option) ([]Referrer, error) {
+	var result []Referrer
+	for _, suffix := range cosignSuffixes {
+		tag := repo.Tag(fmt.Sprintf("%s-%s.%s", subject.Algorithm, subject.Hex, suffix))
+		desc, err := remote.Head(tag, remoteOpts...)
+		if isNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", tag.String(), err)
+		}
+		referrer, err := newReferrer(repo, SourceCosignTag, subject, *desc, remoteOpts)
+		if err != nil {
+			return nil, err
+		}
+		referrer.Tag = tag.TagStr()
+		result = append(result, *referrer)
+	}
+	return result, nil
+}
+
+func newReferrer(
+	repo name.Repository, source ReferrerSource, subject v1.Hash, desc v1.Descriptor, remoteOpts []remote.Option,
+) (*Referrer, error) {
+	referrer := &Referrer{
+		Source:       source,
+		Repository:   repo,
+		Subject:      subject,
+		Descriptor:   desc,
+		ArtifactType: desc.ArtifactType,
+		Annotations:  desc.Annotations,
+	}
+	if !desc.MediaType.IsImage() {
+		return referrer, nil
+	}
+
+	img, err := remote.Image(repo.Digest(desc.Digest.String()), remoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referrer %s: %w", desc.Digest.String(), err)
+	}
+	referrer.Manifest, err = img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referrer manifest %s: %w", desc.Digest.String(), err)
+	}
+	if referrer.ArtifactType == "" {
+		referrer.ArtifactType = referrer.Manifest.Config.ArtifactType
+	}
+	if referrer.ArtifactType == "" {
+		referrer.ArtifactType = string(referrer.Manifest.Config.MediaType)
+	}
+	if len(referrer.Annotations) == 0 {
+		referrer.Annotations = referrer.Manifest.Annotations
+	}
+	return referrer, nil
+}
+
+// FetchBlob opens a blob (usually one of Referrer.Layers) of the referrer's repository
+func FetchBlob(referrer Referrer, blob v1.Descriptor, remoteOpts []remote.Option) (io.ReadCloser, error) {
+	layer, err := remote.Layer(referrer.Repository.Digest(blob.Digest.String()), remoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob %s: %w", blob.Digest.String(), err)
+	}
+	return layer.Compressed()
+}
+
+func isNotFound(err error) bool {
+	var terr *transport.Error
+	return errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound
+}
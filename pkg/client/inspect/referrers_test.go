@@ -0,0 +1,66 @@
+package inspect
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferrers(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.WithReferrersSupport(true)))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	img, err := random.Image(512, 1)
+	require.NoError(t, err)
+	tag, err := name.NewTag(host + "/test/image:latest")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, img))
+	imgDesc, err := partial.Descriptor(img)
+	require.NoError(t, err)
+
+	sbom, err := random.Image(128, 1)
+	require.NoError(t, err)
+	sbom = mutate.ConfigMediaType(sbom, "application/spdx+json")
+	sbom = mutate.Subject(sbom, *imgDesc).(v1.Image)
+	sbomDigest, err := sbom.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag.Context().Digest(sbomDigest.String()), sbom))
+
+	sig, err := random.Image(64, 1)
+	require.NoError(t, err)
+	sigTag := tag.Context().Tag(strings.Replace(imgDesc.Digest.String(), ":", "-", 1) + ".sig")
+	require.NoError(t, remote.Write(sigTag, sig))
+
+	ref, err := reference.ParseNamed(tag.String())
+	require.NoError(t, err)
+	referrers, err := Referrers(ref, nil)
+	require.NoError(t, err)
+	require.Len(t, referrers, 2)
+
+	require.Equal(t, SourceReferrersAPI, referrers[0].Source)
+	require.Equal(t, "application/spdx+json", referrers[0].ArtifactType)
+	require.Equal(t, imgDesc.Digest, referrers[0].Subject)
+	require.Len(t, referrers[0].Layers(), 1)
+
+	require.Equal(t, SourceCosignTag, referrers[1].Source)
+	require.Equal(t, sigTag.TagStr(), referrers[1].Tag)
+
+	blob, err := FetchBlob(referrers[0], referrers[0].Layers()[0], nil)
+	require.NoError(t, err)
+	defer func() { _ = blob.Close() }()
+	data, err := io.ReadAll(blob)
+	require.NoError(t, err)
+	require.EqualValues(t, referrers[0].Layers()[0].Size, len(data))
+}
@@ -0,0 +1,149 @@
+package inspect
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+type Options struct {
+	UncompressedSizes bool
+}
+
+type Opt func(*Options)
+
+// WithUncompressedSizes computes uncompressed layer sizes. This downloads every layer of every manifest.
+func WithUncompressedSizes() Opt {
+	return func(o *Options) {
+		o.UncompressedSizes = true
+	}
+}
+
+// Remote inspects ref in its registry, remoteOpts should carry context and authentication
+func Remote(ref reference.Named, remoteOpts []remote.Option, opts ...Opt) (*Image, error) {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	nameRef, err := name.ParseReference(ref.String())
+	if err != nil {
+		return nil, err
+	}
+	desc, err := remote.Get(nameRef, remoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get descriptor: %w", err)
+	}
+
+	result := &Image{
+		Ref:       ref,
+		Digest:    desc.Digest,
+		MediaType: desc.MediaType,
+	}
+	switch {
+	case desc.MediaType.IsIndex():
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get index: %w", err)
+		}
+		result.Index, err = idx.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get index manifest: %w", err)
+		}
+		for _, entry := range result.Index.Manifests {
+			if !entry.MediaType.IsImage() {
+				continue
+			}
+			img, err := idx.Image(entry.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get image %s: %w", entry.Digest.String(), err)
+			}
+			manifest, err := inspectManifest(img, entry, desc.Digest, options)
+			if err != nil {
+				return nil, err
+			}
+			result.Manifests = append(result.Manifests, *manifest)
+		}
+	case desc.MediaType.IsImage():
+		img, err := desc.Image()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get image: %w", err)
+		}
+		manifest, err := inspectManifest(img, desc.Descriptor, desc.Digest, options)
+		if err != nil {
+			return nil, err
+		}
+		result.Manifests = append(result.Manifests, *manifest)
+	default:
+		return nil, fmt.Errorf("unsupported media type %s", desc.MediaType)
+	}
+	return result, nil
+}
+
+func inspectManifest(img v1.Image, desc v1.Descriptor, topLevel v1.Hash, options Options) (*Manifest, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest %s: %w", desc.Digest.String(), err)
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config of %s: %w", desc.Digest.String(), err)
+	}
+	configName, err := img.ConfigName()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Manifest{
+		Descriptor: desc,
+		Platform:   desc.Platform,
+		Manifest:   manifest,
+		Config:     config,
+		IDs: IDs{
+			Classic:    configName,
+			Containerd: topLevel,
+			Podman:     configName,
+		},
+	}
+	if result.Platform == nil && config.OS != "" {
+		result.Platform = config.Platform()
+	}
+
+	for i, layerDesc := range manifest.Layers {
+		layer := Layer{Descriptor: layerDesc}
+		if i < len(config.RootFS.DiffIDs) {
+			layer.DiffID = config.RootFS.DiffIDs[i]
+		}
+		if options.UncompressedSizes {
+			layer.UncompressedSize, err = uncompressedSize(img, layerDesc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			result.UncompressedSize += layer.UncompressedSize
+		}
+		result.CompressedSize += layerDesc.Size
+		result.Layers = append(result.Layers, layer)
+	}
+	return result, nil
+}
+
+func uncompressedSize(img v1.Image, dig v1.Hash) (int64, error) {
+	layer, err := img.LayerByDigest(dig)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get layer %s: %w", dig.String(), err)
+	}
+	reader, err := layer.Uncompressed()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read layer %s: %w", dig.String(), err)
+	}
+	defer func() { _ = reader.Close() }()
+	size, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read layer %s: %w", dig.String(), err)
+	}
+	return size, nil
+}
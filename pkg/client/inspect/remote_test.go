@@ -0,0 +1,61 @@
+package inspect
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteIndex(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	amd64, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	arm64, err := random.Image(2048, 3)
+	require.NoError(t, err)
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: amd64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: arm64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}}},
+	)
+	tag, err := name.NewTag(host + "/test/image:latest")
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(tag, idx))
+
+	ref, err := reference.ParseNamed(tag.String())
+	require.NoError(t, err)
+	result, err := Remote(ref, nil, WithUncompressedSizes())
+	require.NoError(t, err)
+
+	idxDigest, err := idx.Digest()
+	require.NoError(t, err)
+	require.Equal(t, idxDigest, result.Digest)
+	require.NotNil(t, result.Index)
+	require.Len(t, result.Manifests, 2)
+	require.Len(t, result.Platforms(), 2)
+
+	manifest := result.ForPlatform(v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"})
+	require.NotNil(t, manifest)
+	require.Len(t, manifest.Layers, 3)
+	require.Equal(t, idxDigest, manifest.IDs.Containerd)
+	configName, err := arm64.ConfigName()
+	require.NoError(t, err)
+	require.Equal(t, configName, manifest.IDs.Podman)
+	require.Equal(t, configName, manifest.IDs.For(capabilities.ImageStoreClassic))
+	require.Same(t, manifest, result.BestMatch(v1.Platform{OS: "linux", Architecture: "aarch64"}))
+	require.Nil(t, result.BestMatch(v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}))
+	require.Positive(t, manifest.CompressedSize)
+	require.Greater(t, manifest.UncompressedSize, int64(3*2048))
+}
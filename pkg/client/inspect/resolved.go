@@ -0,0 +1,73 @@
+package inspect
+
+import (
+	"fmt"
+
+	"github.com/distribution/reference"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/platform"
+	"github.com/silenium-dev/docker-wrapper/pkg/errors"
+)
+
+// Resolved is a reference pinned to the digests it pointed to at resolution time
+type Resolved struct {
+	// Canonical is name@digest, with the digest the reference resolves to (index or manifest)
+	Canonical reference.Canonical
+	// Digest is the digest the reference resolves to, the index digest for multi-platform images
+	Digest v1.Hash
+	// ManifestDigest is the digest of the platform manifest, equal to Digest for single-manifest images
+	ManifestDigest v1.Hash
+	ConfigDigest   v1.Hash
+	// Platform is the platform of the selected manifest, it may differ from the requested platform in its variant
+	Platform *v1.Platform
+	// ImageID is the local image id the engine will assign
+	ImageID v1.Hash
+	IDs     IDs
+}
+
+// SelectImage returns the image of ref's descriptor best matching requested and its platform. For indexes, older
+// variants of the requested architecture are selected if there is no exact match, see platform.BestMatch.
+func SelectImage(ref reference.Named, desc *remote.Descriptor, requested v1.Platform) (v1.Image, *v1.Platform, error) {
+	if !desc.MediaType.IsIndex() {
+		img, err := desc.Image()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get image: %w", err)
+		}
+		config, err := img.ConfigFile()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get config: %w", err)
+		}
+		return img, config.Platform(), nil
+	}
+
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get index: %w", err)
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get index manifest: %w", err)
+	}
+	var entries []v1.Descriptor
+	var candidates []v1.Platform
+	for _, entry := range manifest.Manifests {
+		if entry.Platform != nil && entry.MediaType.IsImage() {
+			entries = append(entries, entry)
+			candidates = append(candidates, *entry.Platform)
+		}
+	}
+	i := platform.BestMatch(candidates, requested)
+	if i < 0 {
+		return nil, nil, &errors.NotFoundError{
+			Resource: errors.ResourceTypeImage,
+			ID:       ref.String(),
+			Reason:   fmt.Sprintf("no manifest for platform %s", platform.Format(requested)),
+		}
+	}
+	img, err := idx.Image(entries[i].Digest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get image %s: %w", entries[i].Digest.String(), err)
+	}
+	return img, entries[i].Platform, nil
+}
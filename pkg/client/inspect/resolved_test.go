@@ -0,0 +1,94 @@
+package inspect
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/silenium-dev/docker-wrapper/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectImage(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	images := map[string]v1.Image{}
+	var addenda []mutate.IndexAddendum
+	for _, p := range []v1.Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm", Variant: "v6"},
+		{OS: "linux", Architecture: "arm64", Variant: "v8"},
+	} {
+		img, err := random.Image(1024, 1)
+		require.NoError(t, err)
+		images[p.String()] = img
+		addenda = append(addenda, mutate.IndexAddendum{Add: img, Descriptor: v1.Descriptor{Platform: &p}})
+	}
+	indexTag, err := name.NewTag(host + "/test/index:latest")
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(indexTag, mutate.AppendManifests(empty.Index, addenda...)))
+
+	single, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	config, err := single.ConfigFile()
+	require.NoError(t, err)
+	config.OS, config.Architecture, config.Variant = "linux", "arm", "v7"
+	single, err = mutate.ConfigFile(single, config)
+	require.NoError(t, err)
+	singleTag, err := name.NewTag(host + "/test/single:latest")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(singleTag, single))
+
+	for _, tc := range []struct {
+		name           string
+		tag            name.Tag
+		requested      v1.Platform
+		expectImage    v1.Image
+		expectPlatform v1.Platform
+		expectNotFound bool
+	}{
+		{name: "index exact match", tag: indexTag, requested: v1.Platform{OS: "linux", Architecture: "amd64"},
+			expectImage: images["linux/amd64"], expectPlatform: v1.Platform{OS: "linux", Architecture: "amd64"}},
+		{name: "index normalized match", tag: indexTag, requested: v1.Platform{OS: "linux", Architecture: "aarch64"},
+			expectImage:    images["linux/arm64/v8"],
+			expectPlatform: v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+		{name: "index older variant", tag: indexTag, requested: v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+			expectImage:    images["linux/arm/v6"],
+			expectPlatform: v1.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}},
+		{name: "index without match", tag: indexTag, requested: v1.Platform{OS: "linux", Architecture: "s390x"},
+			expectNotFound: true},
+		{name: "single manifest reports its config platform", tag: singleTag,
+			requested: v1.Platform{OS: "linux", Architecture: "amd64"}, expectImage: single,
+			expectPlatform: v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			desc, err := remote.Get(tc.tag)
+			require.NoError(t, err)
+			ref, err := reference.ParseNamed(tc.tag.String())
+			require.NoError(t, err)
+
+			img, selected, err := SelectImage(ref, desc, tc.requested)
+			if tc.expectNotFound {
+				require.True(t, errors.IsNotFound(err, errors.ResourceTypeImage))
+				return
+			}
+			require.NoError(t, err)
+			expected, err := tc.expectImage.Digest()
+			require.NoError(t, err)
+			actual, err := img.Digest()
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+			require.Equal(t, tc.expectPlatform.String(), selected.String())
+		})
+	}
+}
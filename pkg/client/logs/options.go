@@ -0,0 +1,135 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+)
+
+// TailAll returns all log lines, the default
+const TailAll = -1
+
+// Options of the wrapper's ContainerLogsStream
+type Options struct {
+	Stdout bool
+	Stderr bool
+	Follow bool
+	// Since and Until limit the lines by their timestamp, zero means unlimited
+	Since time.Time
+	Until time.Time
+	// Tail is the number of lines from the end of the logs, TailAll for all
+	Tail int
+	// Details includes the extra attributes of the log driver
+	Details bool
+	// Timestamps parses the timestamp of each line into stream.Message.Timestamp
+	Timestamps bool
+	// Resume reopens a followed stream after a dropped connection from the last timestamp, it implies Timestamps
+	Resume bool
+	// Stream are options of the returned stream, e.g. stream.WithLines
+	Stream []stream.Opt
+}
+
+type Opt func(*Options)
+
+// WithStreams selects the output streams, both are shown by default
+func WithStreams(stdout, stderr bool) Opt {
+	return func(o *Options) {
+		o.Stdout = stdout
+		o.Stderr = stderr
+	}
+}
+
+// WithFollow keeps the stream open and delivers new lines as they are logged
+func WithFollow() Opt {
+	return func(o *Options) {
+		o.Follow = true
+	}
+}
+
+// WithSince only returns lines logged at or after since
+func WithSince(since time.Time) Opt {
+	return func(o *Options) {
+		o.Since = since
+	}
+}
+
+// WithUntil only returns lines logged before until
+func WithUntil(until time.Time) Opt {
+	return func(o *Options) {
+		o.Until = until
+	}
+}
+
+// WithTail only returns the last lines lines, 0 returns only new lines when following
+func WithTail(lines int) Opt {
+	return func(o *Options) {
+		o.Tail = lines
+	}
+}
+
+// WithDetails includes the extra attributes of the log driver
+func WithDetails() Opt {
+	return func(o *Options) {
+		o.Details = true
+	}
+}
+
+// WithTimestamps parses the timestamp of each line
+func WithTimestamps() Opt {
+	return func(o *Options) {
+		o.Timestamps = true
+	}
+}
+
+// WithResume follows the logs and transparently resumes the stream after a dropped connection
+func WithResume() Opt {
+	return func(o *Options) {
+		o.Follow = true
+		o.Timestamps = true
+		o.Resume = true
+	}
+}
+
+// WithStreamOptions configures the returned stream
+func WithStreamOptions(opts ...stream.Opt) Opt {
+	return func(o *Options) {
+		o.Stream = append(o.Stream, opts...)
+	}
+}
+
+// RenderOptions applies opts on top of the defaults (both streams, all lines)
+func RenderOptions(opts []Opt) Options {
+	options := Options{Stdout: true, Stderr: true, Tail: TailAll}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// LogsOptions converts the options to the engine's options
+func (o Options) LogsOptions() container.LogsOptions {
+	result := container.LogsOptions{
+		ShowStdout: o.Stdout,
+		ShowStderr: o.Stderr,
+		Follow:     o.Follow,
+		Timestamps: o.Timestamps || o.Resume,
+		Details:    o.Details,
+		Since:      formatTime(o.Since),
+		Until:      formatTime(o.Until),
+	}
+	if o.Tail >= 0 {
+		result.Tail = strconv.Itoa(o.Tail)
+	}
+	return result
+}
+
+// formatTime formats t as the engines' unix timestamp with nanoseconds
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
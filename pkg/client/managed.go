@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/go-connections/nat"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/managed"
+	podmanclient "github.com/silenium-dev/docker-wrapper/pkg/client/podman/client"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/provider"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/wait"
+)
+
+// reaperStartTimeout bounds the time the reaper takes to accept connections
+const reaperStartTimeout = 30 * time.Second
+
+// NewSession creates a session, which labels the resources created through it and removes them on Close
+func (c *Client) NewSession(ctx context.Context, opts ...managed.Opt) (*managed.Session, error) {
+	options := managed.RenderOptions(opts)
+	if options.ID == "" {
+		options.ID = managed.NewID()
+	}
+	if !options.Reaper {
+		return managed.NewSession(c, options.ID, nil, c.logger), nil
+	}
+	reaper, err := c.startReaper(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return managed.NewSession(c, options.ID, reaper, c.logger), nil
+}
+
+// startReaper starts a reaper sidecar and registers the session with it
+func (c *Client) startReaper(ctx context.Context, options managed.Options) (io.Closer, error) {
+	isPodman, err := c.SystemIsPodman(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if Podman: %w", err)
+	}
+	socket, err := c.engineSocket(ctx, isPodman)
+	if err != nil {
+		return nil, err
+	}
+	reaperImage := provider.ReaperImage(c.imageProvider)
+	imgRef, err := reference.ParseDockerRef(reaperImage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %s: %w", reaperImage, err)
+	}
+	dig, err := c.ImagePullSimple(ctx, imgRef, image.PullOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull image %s: %w", imgRef.String(), err)
+	}
+
+	hostConfig := &container.HostConfig{
+		AutoRemove:      true,
+		Binds:           []string{socket + ":" + managed.ReaperSocket},
+		PublishAllPorts: true,
+		Privileged:      options.ReaperPrivileged,
+	}
+	if isPodman && !options.ReaperPrivileged {
+		// SELinux denies access to the socket otherwise
+		hostConfig.SecurityOpt = []string{"label=disable"}
+	}
+	cont, err := c.ContainerCreate(ctx, &container.Config{
+		Image:        dig.String(),
+		ExposedPorts: nat.PortSet{managed.ReaperPort: {}},
+		Labels:       map[string]string{managed.LabelReaper: "true"},
+	}, hostConfig, nil, nil, "reaper-"+options.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reaper: %w", err)
+	}
+	removeReaper := func() {
+		_ = c.ContainerRemove(context.Background(), cont.ID, container.RemoveOptions{Force: true})
+	}
+	if err := c.ContainerStart(ctx, cont.ID, container.StartOptions{}); err != nil {
+		removeReaper()
+		return nil, fmt.Errorf("failed to start reaper: %w", err)
+	}
+
+	conn, err := c.connectReaper(ctx, cont.ID, managed.ReaperFilter(options.ID))
+	if err != nil {
+		removeReaper()
+		return nil, err
+	}
+	c.logger.Debugf("reaper %s watches session %s", cont.ID, options.ID)
+	return conn, nil
+}
+
+// connectReaper retries connecting until the reaper accepts connections
+func (c *Client) connectReaper(ctx context.Context, id string, filter string) (io.Closer, error) {
+	ctx, cancel := context.WithTimeout(ctx, reaperStartTimeout)
+	defer cancel()
+	ticker := time.NewTicker(wait.DefaultPollInterval)
+	defer ticker.Stop()
+	for {
+		address, err := wait.PublishedAddress(ctx, c, id, managed.ReaperPort, wait.ProbeDaemonHost)
+		if err == nil {
+			var conn io.Closer
+			conn, err = managed.ConnectReaper(ctx, address, filter)
+			if err == nil {
+				return conn, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("reaper %s did not accept connections: %w", id, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// engineSocket returns the path of the engine socket on the engine's host, to be mounted into containers.
+// Rootless engines listen on a per-user socket, Docker Desktop and rootful Docker expose /var/run/docker.sock.
+func (c *Client) engineSocket(ctx context.Context, isPodman bool) (string, error) {
+	if isPodman {
+		podman, err := podmanclient.FromDocker(ctx, c)
+		if err != nil {
+			return "", err
+		}
+		socket, err := podman.RemoteSocket(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get podman socket: %w", err)
+		}
+		return strings.TrimPrefix(socket, "unix://"), nil
+	}
+	caps, err := c.SystemCapabilities(ctx)
+	if err != nil {
+		return "", err
+	}
+	if host := c.DaemonHost(); caps.Rootless && strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://"), nil
+	}
+	return managed.ReaperSocket, nil
+}
@@ -0,0 +1,51 @@
+package managed
+
+const (
+	// LabelSessionID marks containers, networks and volumes created by a session
+	LabelSessionID = "dev.silenium.docker-wrapper.session-id"
+	// LabelReaper marks reaper containers, which remove the resources of a session
+	LabelReaper = "dev.silenium.docker-wrapper.reaper"
+)
+
+// Options of a managed session
+type Options struct {
+	// ID is the session ID, a random ID if empty
+	ID string
+	// Reaper starts a reaper sidecar, which removes the session's resources if the process dies before Close
+	Reaper bool
+	// ReaperPrivileged runs the reaper privileged, which some SELinux setups require for accessing the socket
+	ReaperPrivileged bool
+}
+
+type Opt func(*Options)
+
+// WithID uses id instead of a random session ID, e.g. to share resources between processes
+func WithID(id string) Opt {
+	return func(o *Options) {
+		o.ID = id
+	}
+}
+
+// WithReaper starts a reaper sidecar, its image is taken from the client's ImageProvider
+func WithReaper() Opt {
+	return func(o *Options) {
+		o.Reaper = true
+	}
+}
+
+// WithPrivilegedReaper starts a privileged reaper sidecar
+func WithPrivilegedReaper() Opt {
+	return func(o *Options) {
+		o.Reaper = true
+		o.ReaperPrivileged = true
+	}
+}
+
+// RenderOptions applies opts on top of the zero options
+func RenderOptions(opts []Opt) Options {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
@@ -0,0 +1,60 @@
+package managed
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// ReaperPort is the port reapers listen on for filters
+	ReaperPort = "8080/tcp"
+	// ReaperSocket is where the engine socket is mounted in the reaper container
+	ReaperSocket = "/var/run/docker.sock"
+)
+
+const reaperAck = "ACK"
+
+// ConnectReaper registers the filters of a session with the reaper listening at address.
+// The reaper removes all matching resources once the returned connection is closed or dropped.
+func ConnectReaper(ctx context.Context, address string, filters ...string) (net.Conn, error) {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to reaper at %s: %w", address, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+	for _, filter := range filters {
+		if err := register(conn, reader, filter); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func register(conn net.Conn, reader *bufio.Reader, filter string) error {
+	if _, err := fmt.Fprintf(conn, "%s\n", filter); err != nil {
+		return fmt.Errorf("failed to send filter to reaper: %w", err)
+	}
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read reaper response: %w", err)
+	}
+	if strings.TrimSpace(response) != reaperAck {
+		return fmt.Errorf("reaper rejected filter %q: %s", filter, strings.TrimSpace(response))
+	}
+	return nil
+}
+
+// ReaperFilter returns the reaper filter of the resources of session id
+func ReaperFilter(id string) string {
+	return fmt.Sprintf("label=%s=%s", LabelSessionID, id)
+}
@@ -0,0 +1,158 @@
+package managed
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/silenium-dev/docker-wrapper/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// closeTimeout bounds the cleanup of Close
+const closeTimeout = time.Minute
+
+// Session labels the containers, networks and volumes created through it and removes them on Close
+type Session struct {
+	id     string
+	cli    client.APIClient
+	reaper io.Closer
+	logger *zap.SugaredLogger
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewSession creates a session, reaper is closed after the cleanup on Close and may be nil
+func NewSession(cli client.APIClient, id string, reaper io.Closer, logger *zap.SugaredLogger) *Session {
+	if id == "" {
+		id = NewID()
+	}
+	return &Session{id: id, cli: cli, reaper: reaper, logger: logger}
+}
+
+// NewID returns a random session ID
+func NewID() string {
+	data := make([]byte, 16)
+	_, _ = rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Labels returns the labels of the session's resources, for resources created without the session
+func (s *Session) Labels() map[string]string {
+	return map[string]string{LabelSessionID: s.id}
+}
+
+// Filters returns the filters matching the session's resources
+func (s *Session) Filters() filters.Args {
+	return filters.NewArgs(filters.Arg("label", LabelSessionID+"="+s.id))
+}
+
+func (s *Session) withLabels(labels map[string]string) map[string]string {
+	result := maps.Clone(labels)
+	if result == nil {
+		result = map[string]string{}
+	}
+	maps.Copy(result, s.Labels())
+	return result
+}
+
+// ContainerCreate creates a container labelled with the session ID
+func (s *Session) ContainerCreate(
+	ctx context.Context,
+	config *container.Config,
+	hostConfig *container.HostConfig,
+	networkingConfig *network.NetworkingConfig,
+	platform *v1.Platform,
+	containerName string,
+) (container.CreateResponse, error) {
+	labelled := *config
+	labelled.Labels = s.withLabels(config.Labels)
+	return s.cli.ContainerCreate(ctx, &labelled, hostConfig, networkingConfig, platform, containerName)
+}
+
+// NetworkCreate creates a network labelled with the session ID
+func (s *Session) NetworkCreate(
+	ctx context.Context, name string, options network.CreateOptions,
+) (network.CreateResponse, error) {
+	options.Labels = s.withLabels(options.Labels)
+	return s.cli.NetworkCreate(ctx, name, options)
+}
+
+// VolumeCreate creates a volume labelled with the session ID
+func (s *Session) VolumeCreate(ctx context.Context, options volume.CreateOptions) (volume.Volume, error) {
+	options.Labels = s.withLabels(options.Labels)
+	return s.cli.VolumeCreate(ctx, options)
+}
+
+// Close removes all containers, networks and volumes of the session and stops the reaper
+func (s *Session) Close() error {
+	s.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+		s.closeErr = s.Cleanup(ctx)
+		if s.reaper != nil {
+			if err := s.reaper.Close(); err != nil {
+				err = fmt.Errorf("failed to close reaper connection: %w", err)
+				s.closeErr = stderrors.Join(s.closeErr, err)
+			}
+		}
+	})
+	return s.closeErr
+}
+
+// Cleanup removes all containers, networks and volumes of the session, the session stays usable
+func (s *Session) Cleanup(ctx context.Context) error {
+	var errs []error
+	containers, err := s.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: s.Filters()})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list containers: %w", err))
+	}
+	for _, c := range containers {
+		err := s.cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+		if err != nil && !errors.IsNotFound(err, errors.ResourceTypeContainer) {
+			errs = append(errs, fmt.Errorf("failed to remove container %s: %w", c.ID, err))
+		}
+	}
+
+	networks, err := s.cli.NetworkList(ctx, network.ListOptions{Filters: s.Filters()})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list networks: %w", err))
+	}
+	for _, n := range networks {
+		err := s.cli.NetworkRemove(ctx, n.ID)
+		if err != nil && !errors.IsNotFound(err, errors.ResourceTypeNetwork) {
+			errs = append(errs, fmt.Errorf("failed to remove network %s: %w", n.Name, err))
+		}
+	}
+
+	volumes, err := s.cli.VolumeList(ctx, volume.ListOptions{Filters: s.Filters()})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list volumes: %w", err))
+	}
+	for _, v := range volumes.Volumes {
+		err := s.cli.VolumeRemove(ctx, v.Name, true)
+		if err != nil && !errors.IsNotFound(err, errors.ResourceTypeVolume) {
+			errs = append(errs, fmt.Errorf("failed to remove volume %s: %w", v.Name, err))
+		}
+	}
+	s.logger.Debugf("removed %d containers, %d networks and %d volumes of session %s",
+		len(containers), len(networks), len(volumes.Volumes), s.id)
+	return stderrors.Join(errs...)
+}
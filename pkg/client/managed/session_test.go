@@ -0,0 +1,112 @@
+package managed
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeEngine records created resources, unimplemented methods of client.APIClient panic
+type fakeEngine struct {
+	client.APIClient
+	labels  map[string]string
+	removed []string
+}
+
+func (f *fakeEngine) ContainerCreate(
+	_ context.Context, config *container.Config, _ *container.HostConfig, _ *network.NetworkingConfig,
+	_ *v1.Platform, _ string,
+) (container.CreateResponse, error) {
+	f.labels = config.Labels
+	return container.CreateResponse{ID: "c1"}, nil
+}
+
+func (f *fakeEngine) ContainerList(_ context.Context, options container.ListOptions) ([]container.Summary, error) {
+	if !options.Filters.ExactMatch("label", LabelSessionID+"=session") {
+		return nil, nil
+	}
+	return []container.Summary{{ID: "c1"}}, nil
+}
+
+func (f *fakeEngine) ContainerRemove(_ context.Context, id string, _ container.RemoveOptions) error {
+	f.removed = append(f.removed, "container "+id)
+	return nil
+}
+
+func (f *fakeEngine) NetworkList(context.Context, network.ListOptions) ([]network.Summary, error) {
+	return []network.Summary{{ID: "n1", Name: "net"}}, nil
+}
+
+func (f *fakeEngine) NetworkRemove(_ context.Context, id string) error {
+	f.removed = append(f.removed, "network "+id)
+	return nil
+}
+
+func (f *fakeEngine) VolumeList(context.Context, volume.ListOptions) (volume.ListResponse, error) {
+	return volume.ListResponse{Volumes: []*volume.Volume{{Name: "v1"}}}, nil
+}
+
+func (f *fakeEngine) VolumeRemove(_ context.Context, id string, _ bool) error {
+	f.removed = append(f.removed, "volume "+id)
+	return nil
+}
+
+type closer struct {
+	closed bool
+}
+
+func (c *closer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSession(t *testing.T) {
+	engine := &fakeEngine{}
+	reaper := &closer{}
+	session := NewSession(engine, "session", reaper, zap.NewNop().Sugar())
+
+	config := &container.Config{Labels: map[string]string{"app": "test"}}
+	_, err := session.ContainerCreate(context.Background(), config, nil, nil, nil, "")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"app": "test", LabelSessionID: "session"}, engine.labels)
+	require.Equal(t, map[string]string{"app": "test"}, config.Labels)
+
+	require.NoError(t, session.Close())
+	require.Equal(t, []string{"container c1", "network n1", "volume v1"}, engine.removed)
+	require.True(t, reaper.closed)
+	require.NoError(t, session.Close())
+	require.Len(t, engine.removed, 3)
+}
+
+func TestConnectReaper(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+		_, _ = conn.Write([]byte("ACK\n"))
+		_, _ = conn.Read(make([]byte, 1))
+	}()
+
+	conn, err := ConnectReaper(context.Background(), listener.Addr().String(), ReaperFilter("session"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.Equal(t, "label="+LabelSessionID+"=session\n", <-received)
+}
@@ -0,0 +1,75 @@
+package mirror
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// registriesConf is the subset of containers-registries.conf(5) (version 2) relevant for rewriting
+type registriesConf struct {
+	Registries []registryConf `toml:"registry"`
+}
+
+type registryConf struct {
+	Prefix             string         `toml:"prefix"`
+	Location           string         `toml:"location"`
+	Blocked            bool           `toml:"blocked"`
+	MirrorByDigestOnly bool           `toml:"mirror-by-digest-only"`
+	Mirrors            []endpointConf `toml:"mirror"`
+}
+
+type endpointConf struct {
+	Location string `toml:"location"`
+	// PullFromMirror is one of "all", "digest-only" or "tag-only"
+	PullFromMirror string `toml:"pull-from-mirror"`
+}
+
+// LoadRegistriesConf reads mirror rules from a containers registries.conf file.
+// The first usable mirror of a registry is used, otherwise its location if it differs from the prefix.
+// Blocked registries are skipped, the engine will reject them anyway.
+func LoadRegistriesConf(path string) ([]Rule, error) {
+	var conf registriesConf
+	if _, err := toml.DecodeFile(path, &conf); err != nil {
+		return nil, fmt.Errorf("failed to load registries config %s: %w", path, err)
+	}
+	return rulesFromConf(conf), nil
+}
+
+// ParseRegistriesConf parses mirror rules from the contents of a registries.conf file, see LoadRegistriesConf
+func ParseRegistriesConf(data string) ([]Rule, error) {
+	var conf registriesConf
+	if _, err := toml.Decode(data, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse registries config: %w", err)
+	}
+	return rulesFromConf(conf), nil
+}
+
+func rulesFromConf(conf registriesConf) []Rule {
+	var rules []Rule
+	for _, registry := range conf.Registries {
+		prefix := registry.Prefix
+		if prefix == "" {
+			prefix = registry.Location
+		}
+		if prefix == "" || registry.Blocked {
+			continue
+		}
+		rule := Rule{Prefix: prefix}
+		for _, m := range registry.Mirrors {
+			if m.PullFromMirror == "tag-only" {
+				continue
+			}
+			rule.Location = m.Location
+			rule.DigestOnly = registry.MirrorByDigestOnly || m.PullFromMirror == "digest-only"
+			break
+		}
+		if rule.Location == "" && registry.Location != "" && registry.Location != prefix {
+			rule.Location = registry.Location
+		}
+		if rule.Location != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
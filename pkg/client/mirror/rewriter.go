@@ -0,0 +1,89 @@
+package mirror
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// Rule rewrites references whose fully qualified name starts with Prefix
+type Rule struct {
+	// Prefix is a registry domain or repository prefix (e.g. "docker.io", "docker.io/library", "*.example.com")
+	Prefix string
+	// Location replaces the matched prefix (e.g. "mirror.internal/dockerhub")
+	Location string
+	// DigestOnly restricts the rule to references with a digest, like mirror-by-digest-only in registries.conf
+	DigestOnly bool
+}
+
+func (r Rule) matches(name string) bool {
+	if wildcard, ok := strings.CutPrefix(r.Prefix, "*."); ok {
+		domain, _, _ := strings.Cut(name, "/")
+		return strings.HasSuffix(domain, "."+wildcard)
+	}
+	if !strings.HasPrefix(name, r.Prefix) {
+		return false
+	}
+	rest := name[len(r.Prefix):]
+	return rest == "" || rest[0] == '/'
+}
+
+func (r Rule) apply(name string) string {
+	if strings.HasPrefix(r.Prefix, "*.") {
+		_, path, _ := strings.Cut(name, "/")
+		return r.Location + "/" + path
+	}
+	return r.Location + name[len(r.Prefix):]
+}
+
+// Rewriter maps image references to mirrors. The most specific (longest) matching prefix wins.
+type Rewriter struct {
+	rules []Rule
+}
+
+func NewRewriter(rules ...Rule) *Rewriter {
+	return &Rewriter{rules: rules}
+}
+
+func (r *Rewriter) Rules() []Rule {
+	return r.rules
+}
+
+// Rewrite returns the mirrored reference, or ref itself if no rule matches
+func (r *Rewriter) Rewrite(ref reference.Named) (reference.Named, error) {
+	if r == nil {
+		return ref, nil
+	}
+	_, hasDigest := ref.(reference.Digested)
+	var match *Rule
+	for i, rule := range r.rules {
+		if rule.DigestOnly && !hasDigest {
+			continue
+		}
+		if rule.matches(ref.Name()) && (match == nil || len(rule.Prefix) > len(match.Prefix)) {
+			match = &r.rules[i]
+		}
+	}
+	if match == nil || match.Location == "" {
+		return ref, nil
+	}
+
+	rewritten, err := reference.ParseNamed(match.apply(ref.Name()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite %s with mirror rule %s=%s: %w", ref.String(), match.Prefix, match.Location, err)
+	}
+	if tagged, ok := ref.(reference.Tagged); ok {
+		rewritten, err = reference.WithTag(rewritten, tagged.Tag())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if digested, ok := ref.(reference.Digested); ok {
+		rewritten, err = reference.WithDigest(rewritten, digested.Digest())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rewritten, nil
+}
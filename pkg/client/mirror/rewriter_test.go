@@ -0,0 +1,65 @@
+package mirror
+
+import (
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/stretchr/testify/require"
+)
+
+func rewrite(t *testing.T, rewriter *Rewriter, image string) string {
+	ref, err := reference.ParseDockerRef(image)
+	require.NoError(t, err)
+	rewritten, err := rewriter.Rewrite(ref)
+	require.NoError(t, err)
+	return rewritten.String()
+}
+
+func TestRewrite(t *testing.T) {
+	rewriter := NewRewriter(
+		Rule{Prefix: "docker.io", Location: "mirror.internal/dockerhub"},
+		Rule{Prefix: "docker.io/library", Location: "mirror.internal/official"},
+		Rule{Prefix: "*.example.com", Location: "mirror.internal/example"},
+		Rule{Prefix: "quay.io", Location: "mirror.internal/quay", DigestOnly: true},
+	)
+
+	require.Equal(t, "mirror.internal/official/alpine:3", rewrite(t, rewriter, "alpine:3"))
+	require.Equal(t, "mirror.internal/dockerhub/localstack/localstack:4", rewrite(t, rewriter, "localstack/localstack:4"))
+	require.Equal(t, "mirror.internal/example/foo/bar:latest", rewrite(t, rewriter, "registry.example.com/foo/bar"))
+	require.Equal(t, "docker.io.evil.com/foo:latest", rewrite(t, rewriter, "docker.io.evil.com/foo"))
+	require.Equal(t, "quay.io/prometheus/prometheus:latest", rewrite(t, rewriter, "quay.io/prometheus/prometheus"))
+	digest := "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	require.Equal(t, "mirror.internal/quay/prometheus/prometheus@"+digest, rewrite(t, rewriter, "quay.io/prometheus/prometheus@"+digest))
+}
+
+func TestParseRegistriesConf(t *testing.T) {
+	rules, err := ParseRegistriesConf(`
+unqualified-search-registries = ["docker.io"]
+
+[[registry]]
+prefix = "docker.io"
+location = "docker.io"
+[[registry.mirror]]
+location = "mirror.internal/dockerhub"
+
+[[registry]]
+location = "ghcr.io"
+mirror-by-digest-only = true
+[[registry.mirror]]
+location = "mirror.internal/ghcr"
+
+[[registry]]
+prefix = "registry.k8s.io"
+location = "mirror.internal/k8s"
+
+[[registry]]
+location = "evil.io"
+blocked = true
+`)
+	require.NoError(t, err)
+	require.Equal(t, []Rule{
+		{Prefix: "docker.io", Location: "mirror.internal/dockerhub"},
+		{Prefix: "ghcr.io", Location: "mirror.internal/ghcr", DigestOnly: true},
+		{Prefix: "registry.k8s.io", Location: "mirror.internal/k8s"},
+	}, rules)
+}
@@ -0,0 +1,92 @@
+package platform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// BinfmtDir is where the kernel lists the binfmt_misc registrations
+const BinfmtDir = "/proc/sys/fs/binfmt_misc"
+
+// emulators maps the binfmt_misc handler names registered by qemu-user-static, tonistiigi/binfmt and Docker Desktop
+// to the platforms they emulate
+var emulators = map[string][]v1.Platform{
+	"aarch64":     {{OS: "linux", Architecture: "arm64"}},
+	"arm":         {{OS: "linux", Architecture: "arm", Variant: "v7"}, {OS: "linux", Architecture: "arm", Variant: "v6"}},
+	"x86_64":      {{OS: "linux", Architecture: "amd64"}},
+	"i386":        {{OS: "linux", Architecture: "386"}},
+	"riscv64":     {{OS: "linux", Architecture: "riscv64"}},
+	"ppc64le":     {{OS: "linux", Architecture: "ppc64le"}},
+	"s390x":       {{OS: "linux", Architecture: "s390x"}},
+	"mips64":      {{OS: "linux", Architecture: "mips64"}},
+	"mips64el":    {{OS: "linux", Architecture: "mips64le"}},
+	"loongarch64": {{OS: "linux", Architecture: "loong64"}},
+	"rosetta":     {{OS: "linux", Architecture: "amd64"}},
+}
+
+// FromBinfmt returns the platforms of the enabled emulators registered in dir, usually BinfmtDir
+func FromBinfmt(dir string) ([]v1.Platform, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []v1.Platform
+	for _, entry := range entries {
+		platforms, ok := emulators[strings.TrimPrefix(entry.Name(), "qemu-")]
+		if !ok {
+			continue
+		}
+		enabled, err := binfmtEnabled(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			result = Merge(result, platforms...)
+		}
+	}
+	return result, nil
+}
+
+// binfmtEnabled reads the state from the first line of a registration
+func binfmtEnabled(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return false, scanner.Err()
+	}
+	return strings.TrimSpace(scanner.Text()) == "enabled", nil
+}
+
+// binfmtStatus is the output of the tonistiigi/binfmt image without arguments
+type binfmtStatus struct {
+	Supported []string `json:"supported"`
+	Emulators []string `json:"emulators"`
+}
+
+// ParseBinfmtStatus parses the status the tonistiigi/binfmt image prints, it lists all platforms the kernel runs
+func ParseBinfmtStatus(reader io.Reader) ([]v1.Platform, error) {
+	var status binfmtStatus
+	if err := json.NewDecoder(reader).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode binfmt status: %w", err)
+	}
+	var result []v1.Platform
+	for _, supported := range status.Supported {
+		platform, err := v1.ParsePlatform(supported)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse platform %q: %w", supported, err)
+		}
+		result = Merge(result, *platform)
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package platform
+
+import (
+	"slices"
+
+	"github.com/containerd/platforms"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	v2 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Normalize converts architecture aliases and variants to their canonical form, e.g. aarch64 to arm64 and arm/7 to
+// arm/v7. The arm64 variant v8 is dropped, as it is the default.
+func Normalize(platform v1.Platform) v1.Platform {
+	normalized := platforms.Normalize(v2.Platform{
+		OS:           platform.OS,
+		Architecture: platform.Architecture,
+		OSVersion:    platform.OSVersion,
+		Variant:      platform.Variant,
+		OSFeatures:   platform.OSFeatures,
+	})
+	return v1.Platform{
+		OS:           normalized.OS,
+		Architecture: normalized.Architecture,
+		OSVersion:    normalized.OSVersion,
+		Variant:      normalized.Variant,
+		OSFeatures:   normalized.OSFeatures,
+		Features:     platform.Features,
+	}
+}
+
+// Format returns the os/arch[/variant] form of platform
+func Format(platform v1.Platform) string {
+	return platforms.Format(v2.Platform{OS: platform.OS, Architecture: platform.Architecture, Variant: platform.Variant})
+}
+
+// Equal compares os, architecture and variant of the normalized platforms
+func Equal(a, b v1.Platform) bool {
+	a, b = Normalize(a), Normalize(b)
+	return a.OS == b.OS && a.Architecture == b.Architecture && a.Variant == b.Variant
+}
+
+// variants are the normalized variants of an architecture, newer variants can run images of all older ones.
+// Normalized amd64 v1 and arm64 v8 have no variant, arm without variant is v7.
+var variants = map[string][]string{
+	"amd64": {"v4", "v3", "v2", ""},
+	"arm64": {"v9", ""},
+	"arm":   {"v8", "v7", "v6", "v5"},
+}
+
+// Fallbacks returns the platforms whose images run on platform, in order of preference: platform itself first, then
+// older variants of its architecture, e.g. arm/v7, arm/v6, arm/v5
+func Fallbacks(platform v1.Platform) []v1.Platform {
+	platform = Normalize(platform)
+	result := []v1.Platform{platform}
+	known, ok := variants[platform.Architecture]
+	if !ok {
+		return result
+	}
+	start := slices.Index(known, platform.Variant)
+	if start < 0 {
+		return result
+	}
+	for _, variant := range known[start+1:] {
+		fallback := platform
+		fallback.Variant = variant
+		result = append(result, fallback)
+	}
+	return result
+}
+
+// BestMatch returns the index of the candidate closest to requested, following Fallbacks. It returns -1 if no
+// candidate runs on requested.
+func BestMatch(candidates []v1.Platform, requested v1.Platform) int {
+	for _, fallback := range Fallbacks(requested) {
+		if i := slices.IndexFunc(candidates, func(candidate v1.Platform) bool {
+			return Equal(candidate, fallback)
+		}); i >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Runs reports whether one of hosts runs images of platform, considering older variants
+func Runs(hosts []v1.Platform, platform v1.Platform) bool {
+	for _, host := range hosts {
+		if BestMatch([]v1.Platform{platform}, host) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Native returns the platforms a host of platform runs without emulation, in order of preference.
+// 32-bit arm is not included for arm64 hosts, as not all arm64 CPUs support it (e.g. Apple silicon).
+func Native(platform v1.Platform) []v1.Platform {
+	result := Fallbacks(platform)
+	if platform := Normalize(platform); platform.Architecture == "amd64" {
+		result = append(result, v1.Platform{OS: platform.OS, Architecture: "386"})
+	}
+	return result
+}
+
+// Merge appends the platforms which are not yet contained to list
+func Merge(list []v1.Platform, platforms ...v1.Platform) []v1.Platform {
+	for _, platform := range platforms {
+		if !slices.ContainsFunc(list, func(p v1.Platform) bool { return Equal(p, platform) }) {
+			list = append(list, Normalize(platform))
+		}
+	}
+	return list
+}
@@ -0,0 +1,77 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, s string) v1.Platform {
+	p, err := v1.ParsePlatform(s)
+	require.NoError(t, err)
+	return *p
+}
+
+func TestBestMatch(t *testing.T) {
+	candidates := []v1.Platform{
+		parse(t, "linux/amd64"), parse(t, "linux/arm64/v8"), parse(t, "linux/arm/v6"), parse(t, "linux/arm/v7"),
+	}
+	for requested, expected := range map[string]int{
+		"linux/amd64":    0,
+		"linux/amd64/v3": 0,
+		"linux/arm64":    1,
+		"linux/aarch64":  1,
+		"linux/arm/v7":   3,
+		"linux/arm/v8":   3,
+		"linux/arm/v6":   2,
+		"linux/arm/v5":   -1,
+		"linux/riscv64":  -1,
+		"windows/amd64":  -1,
+	} {
+		require.Equal(t, expected, BestMatch(candidates, parse(t, requested)), requested)
+	}
+	// arm/v7 falls back to v6
+	require.Equal(t, 0, BestMatch(candidates[2:3], parse(t, "linux/arm/v7")))
+}
+
+func TestNative(t *testing.T) {
+	amd64 := Native(parse(t, "linux/amd64"))
+	require.True(t, Runs(amd64, parse(t, "linux/386")))
+	require.False(t, Runs(amd64, parse(t, "linux/arm64")))
+
+	arm64 := Native(parse(t, "linux/arm64"))
+	require.True(t, Runs(arm64, parse(t, "linux/arm64/v8")))
+	require.False(t, Runs(arm64, parse(t, "linux/arm/v7")))
+}
+
+func TestFromBinfmt(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"register":     "",
+		"status":       "enabled",
+		"qemu-aarch64": "enabled\ninterpreter /usr/bin/qemu-aarch64\n",
+		"qemu-riscv64": "disabled\ninterpreter /usr/bin/qemu-riscv64\n",
+		"qemu-arm":     "enabled\ninterpreter /usr/bin/qemu-arm\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	found, err := FromBinfmt(dir)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []v1.Platform{
+		parse(t, "linux/arm64"), parse(t, "linux/arm/v7"), parse(t, "linux/arm/v6"),
+	}, found)
+}
+
+func TestParseBinfmtStatus(t *testing.T) {
+	found, err := ParseBinfmtStatus(strings.NewReader(
+		`{"supported":["linux/amd64","linux/386","linux/arm64","linux/arm/v7"],"emulators":["qemu-aarch64","qemu-arm"]}`,
+	))
+	require.NoError(t, err)
+	require.Equal(t, []v1.Platform{
+		parse(t, "linux/amd64"), parse(t, "linux/386"), parse(t, "linux/arm64"), parse(t, "linux/arm/v7"),
+	}, found)
+}
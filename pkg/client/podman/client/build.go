@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/build"
+)
+
+// ImageBuild builds an image with the libpod build endpoint, which accepts build secrets in contrast to the
+// compat endpoint used by the docker client. Secrets are in buildah format (id=...,src=...), with src relative
+// to the context, podman moves them out of the context before building.
+func (p *Podman) ImageBuild(
+	ctx context.Context, buildContext io.Reader, opts build.ImageBuildOptions, secrets []string,
+) (io.ReadCloser, error) {
+	query, err := buildQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) > 0 {
+		secretsJSON, err := json.Marshal(secrets)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("secrets", string(secretsJSON))
+	}
+
+	authJSON, err := json.Marshal(opts.AuthConfigs)
+	if err != nil {
+		return nil, err
+	}
+	headers := http.Header{}
+	headers.Set("X-Registry-Config", base64.URLEncoding.EncodeToString(authJSON))
+	headers.Set("Content-Type", "application/x-tar")
+
+	resp, err := p.conn.DoRequest(ctx, buildContext, http.MethodPost, "/build", query, headers)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, resp.Process(nil)
+	}
+	return resp.Body, nil
+}
+
+// buildQuery encodes the options podman understands like the docker client does, empty values are omitted
+func buildQuery(opts build.ImageBuildOptions) (url.Values, error) {
+	query := url.Values{}
+	if len(opts.Tags) > 0 {
+		query["t"] = opts.Tags
+	}
+	if opts.SuppressOutput {
+		query.Set("q", "1")
+	}
+	if opts.RemoteContext != "" {
+		query.Set("remote", opts.RemoteContext)
+	}
+	if opts.NoCache {
+		query.Set("nocache", "1")
+	}
+	if !opts.Remove {
+		query.Set("rm", "0")
+	}
+	if opts.ForceRemove {
+		query.Set("forcerm", "1")
+	}
+	if opts.PullParent {
+		query.Set("pull", "1")
+	}
+	if opts.Squash {
+		query.Set("squash", "1")
+	}
+	if opts.CPUSetCPUs != "" {
+		query.Set("cpusetcpus", opts.CPUSetCPUs)
+	}
+	if opts.NetworkMode != "" && opts.NetworkMode != "default" {
+		query.Set("networkmode", opts.NetworkMode)
+	}
+	if opts.CPUSetMems != "" {
+		query.Set("cpusetmems", opts.CPUSetMems)
+	}
+	if opts.CPUShares != 0 {
+		query.Set("cpushares", strconv.FormatInt(opts.CPUShares, 10))
+	}
+	if opts.CPUQuota != 0 {
+		query.Set("cpuquota", strconv.FormatInt(opts.CPUQuota, 10))
+	}
+	if opts.CPUPeriod != 0 {
+		query.Set("cpuperiod", strconv.FormatInt(opts.CPUPeriod, 10))
+	}
+	if opts.Memory != 0 {
+		query.Set("memory", strconv.FormatInt(opts.Memory, 10))
+	}
+	if opts.MemorySwap != 0 {
+		query.Set("memswap", strconv.FormatInt(opts.MemorySwap, 10))
+	}
+	if opts.ShmSize != 0 {
+		query.Set("shmsize", strconv.FormatInt(opts.ShmSize, 10))
+	}
+	if opts.Dockerfile != "" {
+		query.Set("dockerfile", opts.Dockerfile)
+	}
+	if opts.Target != "" {
+		query.Set("target", opts.Target)
+	}
+	if opts.Platform != "" {
+		query.Set("platform", strings.ToLower(opts.Platform))
+	}
+	for key, value := range map[string]any{
+		"ulimits":   opts.Ulimits,
+		"buildargs": opts.BuildArgs,
+		"labels":    opts.Labels,
+		"cachefrom": opts.CacheFrom,
+		// podman expects a JSON array, in contrast to docker
+		"extrahosts": opts.ExtraHosts,
+	} {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if string(encoded) != "null" && string(encoded) != "{}" && string(encoded) != "[]" {
+			query.Set(key, string(encoded))
+		}
+	}
+	return query, nil
+}
@@ -28,13 +28,30 @@ func FromDocker(
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve podman connection: %w", err)
 	}
+	return newPodman(cli, conn, ver), nil
+}
+
+// FromPodmanHost derives a podman connection from a docker remote already known to be podman.
+// Unlike FromDocker it does not call SystemIsPodman, so it can be used while detecting the engine.
+func FromPodmanHost(
+	ctx context.Context,
+	cli api.ClientWrapper,
+) (*Podman, error) {
+	conn, ver, err := connectPodman(cli, ctx, cli.Logger())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve podman connection: %w", err)
+	}
+	return newPodman(cli, conn, ver), nil
+}
+
+func newPodman(cli api.ClientWrapper, conn *bindings.Connection, ver *semver.Version) *Podman {
 	return &Podman{
 		cli:          cli,
 		conn:         conn,
 		ver:          ver,
 		logger:       cli.Logger(),
 		authProvider: cli.AuthProvider(),
-	}, nil
+	}
 }
 
 func (p *Podman) AuthProvider() provider.AuthProvider {
@@ -21,7 +21,13 @@ func getPodmanConnection(cli api.ClientWrapper, ctx context.Context, logger *zap
 		return nil, nil, ErrNotPodman
 	}
 	logger.Debugf("remote is podman")
+	return connectPodman(cli, ctx, logger)
+}
 
+// connectPodman connects to the libpod API of the docker remote, without checking it is podman
+func connectPodman(cli api.ClientWrapper, ctx context.Context, logger *zap.SugaredLogger) (
+	*bindings.Connection, *semver.Version, error,
+) {
 	cliHost := cli.DaemonHost()
 	logger.Debugf("trying to connect directly to docker host: %s", cliHost)
 
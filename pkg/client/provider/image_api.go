@@ -1,6 +1,34 @@
 package provider
 
 type ImageProvider interface {
-	// GetDnsUtilImage returns an OCI image having dig preinstalled (for example: "registry.k8s.io/e2e-test-images/agnhost:2.39")
+	// GetDnsUtilImage returns an OCI image having sh and getent preinstalled (for example: "registry.k8s.io/e2e-test-images/agnhost:2.39")
 	GetDnsUtilImage() string
 }
+
+// ReaperImageProvider is implemented by image providers which override the reaper image of managed clients
+type ReaperImageProvider interface {
+	// GetReaperImage returns a ryuk compatible reaper image (for example: "testcontainers/ryuk:0.11.0")
+	GetReaperImage() string
+}
+
+// BinfmtImageProvider is implemented by image providers which override the image of the platform probe
+type BinfmtImageProvider interface {
+	// GetBinfmtImage returns an image printing the binfmt_misc status as JSON (for example: "tonistiigi/binfmt:qemu-v7.0.0-28")
+	GetBinfmtImage() string
+}
+
+// ReaperImage returns the reaper image of provider, the default one if it doesn't implement ReaperImageProvider
+func ReaperImage(provider ImageProvider) string {
+	if reaper, ok := provider.(ReaperImageProvider); ok {
+		return reaper.GetReaperImage()
+	}
+	return (&defaultImageProvider{}).GetReaperImage()
+}
+
+// BinfmtImage returns the binfmt image of provider, the default one if it doesn't implement BinfmtImageProvider
+func BinfmtImage(provider ImageProvider) string {
+	if binfmt, ok := provider.(BinfmtImageProvider); ok {
+		return binfmt.GetBinfmtImage()
+	}
+	return (&defaultImageProvider{}).GetBinfmtImage()
+}
@@ -7,6 +7,14 @@ func (d *defaultImageProvider) GetDnsUtilImage() string {
 	return "registry.k8s.io/e2e-test-images/agnhost:2.39"
 }
 
+func (d *defaultImageProvider) GetReaperImage() string {
+	return "testcontainers/ryuk:0.11.0"
+}
+
+func (d *defaultImageProvider) GetBinfmtImage() string {
+	return "tonistiigi/binfmt:qemu-v7.0.0-28"
+}
+
 func DefaultImageProvider() ImageProvider {
 	return &defaultImageProvider{}
 }
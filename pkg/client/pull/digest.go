@@ -0,0 +1,34 @@
+package pull
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/inspect"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+)
+
+// CheckDigest replaces digest events which don't match the resolved digests with an error.
+// Depending on the engine, the index or the platform manifest digest is reported.
+func CheckDigest(ctx context.Context, resolved *inspect.Resolved, ch chan events.PullEvent) chan events.PullEvent {
+	out := make(chan events.PullEvent)
+	go func() {
+		defer close(out)
+		for event := range ch {
+			if dig, ok := event.(*events.Digest); ok {
+				reported := dig.Digest.String()
+				if reported != resolved.Digest.String() && reported != resolved.ManifestDigest.String() {
+					event = &events.PullError{
+						Error: fmt.Sprintf("engine pulled %s, expected %s", reported, resolved.Digest.String()),
+					}
+				}
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,56 @@
+package pull
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/inspect"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDigest(t *testing.T) {
+	const (
+		indexDigest    = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		manifestDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+		otherDigest    = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	)
+	resolved := &inspect.Resolved{
+		Digest:         v1.Hash{Algorithm: "sha256", Hex: indexDigest[len("sha256:"):]},
+		ManifestDigest: v1.Hash{Algorithm: "sha256", Hex: manifestDigest[len("sha256:"):]},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		reported digest.Digest
+		accepted bool
+	}{
+		// depending on the engine, the index or the platform manifest digest is reported
+		{name: "index digest", reported: indexDigest, accepted: true},
+		{name: "platform manifest digest", reported: manifestDigest, accepted: true},
+		{name: "other digest", reported: otherDigest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ch := make(chan events.PullEvent, 2)
+			ch <- &events.Digest{Digest: tc.reported}
+			ch <- &events.DownloadedNewerImage{}
+			close(ch)
+
+			var checked []events.PullEvent
+			for event := range CheckDigest(context.Background(), resolved, ch) {
+				checked = append(checked, event)
+			}
+			require.Len(t, checked, 2)
+			if tc.accepted {
+				require.Equal(t, &events.Digest{Digest: tc.reported}, checked[0])
+				return
+			}
+			pullErr, ok := checked[0].(*events.PullError)
+			require.True(t, ok, "a mismatch must become a pull error")
+			require.Contains(t, pullErr.Error, otherDigest)
+			require.Contains(t, pullErr.Error, indexDigest)
+		})
+	}
+}
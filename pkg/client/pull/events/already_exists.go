@@ -15,3 +15,6 @@ func (a *AlreadyExists) LayerId() string {
 }
 
 const AlreadyExistsStatus = "Already exists"
+
+// ExistsStatus is reported instead of AlreadyExistsStatus by docker's containerd image store
+const ExistsStatus = "Exists"
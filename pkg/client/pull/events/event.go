@@ -52,7 +52,7 @@ func Parse(event base.PullProgressEvent) (PullEvent, error) {
 	errorEvent := PullError{Error: event.Error}
 
 	switch event.Status {
-	case AlreadyExistsStatus:
+	case AlreadyExistsStatus, ExistsStatus:
 		return &AlreadyExists{layer}, nil
 	case PullingFSLayerStatus:
 		return &PullingFSLayer{layer}, nil
@@ -103,5 +103,5 @@ func Parse(event base.PullProgressEvent) (PullEvent, error) {
 			return &final, nil
 		}
 	}
-	return nil, fmt.Errorf("unknown pull status: %s", event.Status)
+	return &UnknownEvent{event}, nil
 }
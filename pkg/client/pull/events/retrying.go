@@ -0,0 +1,19 @@
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// Retrying is emitted by the wrapper (not the engine) before a failed pull is retried
+type Retrying struct {
+	// Attempt is the number of the upcoming attempt, starting at 2 for the first retry
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+	Reason      string
+}
+
+func (r *Retrying) String() string {
+	return fmt.Sprintf("Retrying in %s (attempt %d/%d): %s", r.Delay.String(), r.Attempt, r.MaxAttempts, r.Reason)
+}
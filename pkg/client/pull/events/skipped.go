@@ -0,0 +1,19 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// Skipped is emitted by the wrapper (not the engine) instead of a pull, when the pull policy allows using the local
+// image
+type Skipped struct {
+	// Digest is the repo digest of the local image, empty if it has none
+	Digest digest.Digest
+	Reason string
+}
+
+func (s *Skipped) String() string {
+	return fmt.Sprintf("Pull skipped: %s", s.Reason)
+}
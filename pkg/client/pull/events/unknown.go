@@ -0,0 +1,19 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/base"
+)
+
+// UnknownEvent is emitted for statuses Parse does not recognise, so that new daemon messages don't break consumers
+type UnknownEvent struct {
+	Raw base.PullProgressEvent
+}
+
+func (u *UnknownEvent) String() string {
+	if u.Raw.ID != "" {
+		return fmt.Sprintf("[%s] %s", u.Raw.ID, u.Raw.Status)
+	}
+	return u.Raw.Status
+}
@@ -0,0 +1,89 @@
+package pull
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Images looks up the local and remote images a pull policy decides on
+type Images struct {
+	// Inspect returns the local image, a not-found error if there is none
+	Inspect func(ctx context.Context, ref reference.Named) (image.InspectResponse, error)
+	// Head returns the digest ref currently resolves to in its registry
+	Head   func(ctx context.Context, ref reference.Named) (v1.Hash, error)
+	Logger *zap.SugaredLogger
+}
+
+// LocalImage returns the local image if policy allows skipping the pull of ref, nil if it has to be pulled.
+// mirrored is the reference ref is pulled with, the remote digest is looked up with it.
+func (i Images) LocalImage(
+	ctx context.Context, ref, mirrored reference.Named, policy Policy,
+) (*image.InspectResponse, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if policy == PolicyAlways {
+		return nil, nil
+	}
+
+	local, err := i.Inspect(ctx, ref)
+	if _, pinned := ref.(reference.Canonical); pinned && mirrored.String() != ref.String() &&
+		errors.IsNotFound(err, errors.ResourceTypeImage) {
+		// digest references can't be tagged, images pulled from a mirror are only known by the mirror's name
+		local, err = i.Inspect(ctx, mirrored)
+	}
+	if errors.IsNotFound(err, errors.ResourceTypeImage) {
+		if policy == PolicyNever {
+			return nil, &errors.NotFoundError{
+				Resource: errors.ResourceTypeImage, ID: ref.String(), Reason: "pull policy is never",
+			}
+		}
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to inspect local image %s: %w", ref.String(), err)
+	}
+
+	if policy != PolicyNewer {
+		return &local, nil
+	}
+
+	remote, err := i.Head(ctx, mirrored)
+	if err != nil {
+		// like podman, the local image is used if the registry can't be reached
+		i.Logger.Warnf("failed to get remote digest of %s, using local image: %v", mirrored.String(), err)
+		return &local, nil
+	}
+	if slices.Contains(RepoDigests(local, ref, mirrored), digest.Digest(remote.String())) {
+		return &local, nil
+	}
+	i.Logger.Debugf("remote image %s is newer than local image %s", remote.String(), local.ID)
+	return nil, nil
+}
+
+// RepoDigests returns the digests of the local image in the repositories of refs, e.g. the original and the
+// mirrored reference. Podman records both the index and the platform manifest digest.
+func RepoDigests(local image.InspectResponse, refs ...reference.Named) []digest.Digest {
+	var result []digest.Digest
+	for _, rd := range local.RepoDigests {
+		parsed, err := reference.ParseNormalizedNamed(rd)
+		if err != nil {
+			continue
+		}
+		canonical, ok := parsed.(reference.Canonical)
+		if !ok {
+			continue
+		}
+		if slices.ContainsFunc(refs, func(ref reference.Named) bool { return ref.Name() == parsed.Name() }) {
+			result = append(result, canonical.Digest())
+		}
+	}
+	return result
+}
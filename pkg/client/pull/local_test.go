@@ -0,0 +1,97 @@
+package pull
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	wrappererrors "github.com/silenium-dev/docker-wrapper/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	localDigest  = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	remoteDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+// fakeImages has the local images by reference and a fixed remote digest
+func fakeImages(local map[string]image.InspectResponse, remote string, heads *int) Images {
+	return Images{
+		Inspect: func(_ context.Context, ref reference.Named) (image.InspectResponse, error) {
+			if img, ok := local[ref.String()]; ok {
+				return img, nil
+			}
+			return image.InspectResponse{}, &wrappererrors.NotFoundError{Resource: wrappererrors.ResourceTypeImage, ID: ref.String()}
+		},
+		Head: func(context.Context, reference.Named) (v1.Hash, error) {
+			*heads++
+			if remote == "" {
+				return v1.Hash{}, errors.New("registry unreachable")
+			}
+			return v1.NewHash(remote)
+		},
+		Logger: zap.NewNop().Sugar(),
+	}
+}
+
+func TestLocalImage(t *testing.T) {
+	original, err := reference.ParseNormalizedNamed("alpine:latest")
+	require.NoError(t, err)
+	mirrored, err := reference.ParseNormalizedNamed("mirror.local/library/alpine:latest")
+	require.NoError(t, err)
+	pinned, err := reference.ParseNormalizedNamed("alpine@" + localDigest)
+	require.NoError(t, err)
+	pinnedMirror, err := reference.ParseNormalizedNamed("mirror.local/library/alpine@" + localDigest)
+	require.NoError(t, err)
+
+	// images pulled from the mirror carry the mirror's repo digest
+	present := image.InspectResponse{ID: "sha256:abc", RepoDigests: []string{"mirror.local/library/alpine@" + localDigest}}
+
+	for _, tc := range []struct {
+		name          string
+		ref, mirrored reference.Named
+		policy        Policy
+		local         map[string]image.InspectResponse
+		remote        string
+		expectLocal   bool
+		expectErr     bool
+		expectHeads   int
+	}{
+		{name: "always pulls", ref: original, mirrored: original, policy: PolicyAlways,
+			local: map[string]image.InspectResponse{original.String(): present}},
+		{name: "missing uses local", ref: original, mirrored: original, policy: PolicyMissing,
+			local: map[string]image.InspectResponse{original.String(): present}, expectLocal: true},
+		{name: "missing pulls absent", ref: original, mirrored: original, policy: PolicyMissing},
+		{name: "never fails absent", ref: original, mirrored: original, policy: PolicyNever, expectErr: true},
+		{name: "never uses local", ref: original, mirrored: original, policy: PolicyNever,
+			local: map[string]image.InspectResponse{original.String(): present}, expectLocal: true},
+		{name: "newer keeps matching mirror digest", ref: original, mirrored: mirrored, policy: PolicyNewer,
+			local: map[string]image.InspectResponse{original.String(): present}, remote: localDigest,
+			expectLocal: true, expectHeads: 1},
+		{name: "newer pulls changed digest", ref: original, mirrored: mirrored, policy: PolicyNewer,
+			local: map[string]image.InspectResponse{original.String(): present}, remote: remoteDigest,
+			expectHeads: 1},
+		{name: "newer keeps local if registry fails", ref: original, mirrored: mirrored, policy: PolicyNewer,
+			local: map[string]image.InspectResponse{original.String(): present}, expectLocal: true, expectHeads: 1},
+		{name: "missing finds pinned image by mirror name", ref: pinned, mirrored: pinnedMirror, policy: PolicyMissing,
+			local: map[string]image.InspectResponse{pinnedMirror.String(): present}, expectLocal: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			heads := 0
+			local, err := fakeImages(tc.local, tc.remote, &heads).LocalImage(
+				context.Background(), tc.ref, tc.mirrored, tc.policy,
+			)
+			if tc.expectErr {
+				require.True(t, wrappererrors.IsNotFound(err, wrappererrors.ResourceTypeImage))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectLocal, local != nil)
+			require.Equal(t, tc.expectHeads, heads)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package pull
+
+import (
+	"github.com/distribution/reference"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/opencontainers/go-digest"
+)
+
+// Request describes one image of a multi-image pull
+type Request struct {
+	Ref reference.Named
+	// Platform to pull, the engine's default platform is used if nil
+	Platform *v1.Platform
+	Opts     []Opt
+}
+
+// Result is the outcome of one image of a multi-image pull
+type Result struct {
+	Ref reference.Named
+	// ImageID is the local image id, as computed before the pull
+	ImageID v1.Hash
+	// Digest is the manifest digest reported by the engine
+	Digest digest.Digest
+	// Canonical is Ref pinned to Digest, nil if the engine reported no digest
+	Canonical       reference.Canonical
+	DownloadedNewer bool
+	Err             error
+}
@@ -0,0 +1,73 @@
+package pull
+
+import (
+	"github.com/distribution/reference"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/podman/containers/config"
+)
+
+// Options are per-call options of the wrapper's ImagePull* methods, which override the client defaults
+type Options struct {
+	Retry *RetryPolicy
+	// Policy decides whether the registry is contacted at all, defaults to PolicyAlways
+	Policy Policy
+	// Verifier gates pulls, nil disables verification. Local images used due to the pull policy are verified by their
+	// repo digest, images without one are rejected.
+	Verifier Verifier
+}
+
+// Verifier checks an image before the engine pulls it, e.g. verify.CosignVerifier
+type Verifier interface {
+	// Verify is called with the digest ref resolves to in its registry, remoteOpts carry context and
+	// authentication. A non-nil error aborts the pull.
+	Verify(ref reference.Named, digest v1.Hash, remoteOpts []remote.Option) error
+}
+
+// Policy is podman's pull policy, see config.PullPolicy
+type Policy = config.PullPolicy
+
+const (
+	PolicyAlways  = config.PullPolicyAlways
+	PolicyMissing = config.PullPolicyMissing
+	PolicyNever   = config.PullPolicyNever
+	PolicyNewer   = config.PullPolicyNewer
+)
+
+type Opt func(*Options)
+
+// WithRetry retries failed pulls according to policy
+func WithRetry(policy RetryPolicy) Opt {
+	return func(o *Options) {
+		o.Retry = &policy
+	}
+}
+
+// WithoutRetry disables retries, even if the client has a retry policy configured
+func WithoutRetry() Opt {
+	return func(o *Options) {
+		o.Retry = &RetryPolicy{MaxAttempts: 1}
+	}
+}
+
+// WithPolicy sets the pull policy (always, missing, never or newer)
+func WithPolicy(policy Policy) Opt {
+	return func(o *Options) {
+		o.Policy = policy
+	}
+}
+
+// WithVerifier verifies images with verifier before pulling them
+func WithVerifier(verifier Verifier) Opt {
+	return func(o *Options) {
+		o.Verifier = verifier
+	}
+}
+
+// RenderOptions applies opts on top of defaults
+func RenderOptions(defaults Options, opts []Opt) Options {
+	for _, opt := range opts {
+		opt(&defaults)
+	}
+	return defaults
+}
@@ -2,14 +2,20 @@ package pull
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/base"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
-	"io"
-	"log"
 )
 
+// ParseStream decodes the JSON message stream of an image pull into events.
+// Malformed lines are reported as *events.DecodeError and unrecognised statuses as *events.UnknownEvent,
+// the channel is closed when the reader is exhausted or ctx is done.
 func ParseStream(ctx context.Context, reader io.ReadCloser) chan events.PullEvent {
 	result := make(chan events.PullEvent)
 	go parseEvents(ctx, reader, result)
@@ -20,24 +26,57 @@ func parseEvents(ctx context.Context, reader io.ReadCloser, ch chan events.PullE
 	defer close(ch)
 	defer func() { _ = reader.Close() }()
 
+	send := func(event events.PullEvent) bool {
+		select {
+		case ch <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
 	scan := bufio.NewScanner(reader)
 	for scan.Scan() {
-		var raw base.PullProgressEvent
-		err := json.Unmarshal(scan.Bytes(), &raw)
-		if err != nil {
-			panic(err)
+		line := scan.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
 		}
-		event, err := events.Parse(raw)
+		event, err := parseLine(line)
 		if err != nil {
-			panic(err)
+			event = &events.DecodeError{Line: bytes.Clone(line), Err: err}
 		}
-		select {
-		case ch <- event:
-		case <-ctx.Done():
+		if !send(event) {
 			return
 		}
 	}
-	if err := scan.Err(); err != nil {
-		log.Printf("error reading pull stream: %v", err)
+	if err := scan.Err(); err != nil && ctx.Err() == nil {
+		send(&events.DecodeError{Err: err})
+	}
+}
+
+func parseLine(line []byte) (events.PullEvent, error) {
+	var raw base.PullProgressEvent
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, err
+	}
+	return events.Parse(raw)
+}
+
+// Wait drains the pull stream and returns the error the pull ended with: a pull error or a stream which could not be
+// read any further. A retry clears the error of the failed attempt.
+func Wait(ch chan events.PullEvent) error {
+	var err error
+	for event := range ch {
+		switch event := event.(type) {
+		case *events.PullError:
+			err = errors.New(event.Error)
+		case *events.DecodeError:
+			if len(event.Line) == 0 {
+				err = fmt.Errorf("failed to read pull stream: %w", event.Err)
+			}
+		case *events.Retrying:
+			err = nil
+		}
 	}
+	return err
 }
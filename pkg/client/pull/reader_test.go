@@ -0,0 +1,59 @@
+package pull
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStreamToleratesBadInput(t *testing.T) {
+	input := strings.Join([]string{
+		`{"status":"Pulling from library/alpine","id":"latest"}`,
+		`{"status":"Pulling fs layer","id":"abc"}`,
+		`not json`,
+		`{"status":"Some brand new status","id":"abc"}`,
+		`{"status":"Digest: sha256:0000000000000000000000000000000000000000000000000000000000000000"}`,
+	}, "\n")
+
+	var parsed []events.PullEvent
+	for event := range ParseStream(context.Background(), io.NopCloser(strings.NewReader(input))) {
+		parsed = append(parsed, event)
+	}
+
+	require.Len(t, parsed, 5)
+	require.IsType(t, &events.PullStarted{}, parsed[0])
+	require.IsType(t, &events.PullingFSLayer{}, parsed[1])
+	require.IsType(t, &events.DecodeError{}, parsed[2])
+	require.Equal(t, "not json", string(parsed[2].(*events.DecodeError).Line))
+	require.IsType(t, &events.UnknownEvent{}, parsed[3])
+	require.Equal(t, "Some brand new status", parsed[3].(*events.UnknownEvent).Raw.Status)
+	require.IsType(t, &events.Digest{}, parsed[4])
+}
+
+func TestWait(t *testing.T) {
+	started := `{"status":"Pulling from library/alpine","id":"latest"}` + "\n"
+	complete := started + `{"status":"Status: Downloaded newer image for alpine:latest"}`
+
+	require.NoError(t, Wait(ParseStream(context.Background(), io.NopCloser(strings.NewReader(complete)))))
+
+	// a stream failing mid-pull must not look like a completed pull
+	interrupted := io.NopCloser(io.MultiReader(strings.NewReader(started), failingReader{}))
+	require.ErrorContains(t, Wait(ParseStream(context.Background(), interrupted)), "connection reset")
+
+	failed := started + `{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`
+	require.ErrorContains(t, Wait(ParseStream(context.Background(), io.NopCloser(strings.NewReader(failed)))),
+		"manifest unknown")
+
+	// a retry clears the failure of the previous attempt
+	ch := make(chan events.PullEvent, 3)
+	ch <- &events.DecodeError{Err: errors.New("connection reset")}
+	ch <- &events.Retrying{Attempt: 2, MaxAttempts: 2}
+	ch <- &events.DownloadedNewerImage{}
+	close(ch)
+	require.NoError(t, Wait(ch))
+}
@@ -0,0 +1,246 @@
+package pull
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/state"
+)
+
+// RetryPolicy configures retries of failed pulls
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one, values <= 1 disable retries
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Multiplier is the backoff growth factor per attempt, 2 if unset
+	Multiplier float64
+	// Jitter randomizes each backoff by up to +/- this fraction (0-1)
+	Jitter float64
+	// Retryable classifies errors, IsRetryable is used if nil
+	Retryable func(err error) bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+func (p RetryPolicy) Enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// Backoff returns the delay before the given attempt (2 for the first retry)
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(max(attempt-2, 0)))
+	if p.MaxBackoff > 0 {
+		delay = min(delay, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(max(delay, 0))
+}
+
+// ShouldRetry reports whether another attempt is allowed after the given (failed) attempt
+func (p RetryPolicy) ShouldRetry(attempt int, err error) bool {
+	if attempt >= p.MaxAttempts || err == nil {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+var permanentMessages = []string{
+	"not found",
+	"manifest unknown",
+	"unauthorized",
+	"denied",
+	"authentication required",
+	"invalid reference format",
+	"no matching manifest",
+}
+
+var transientMessages = []string{
+	"toomanyrequests",
+	"too many requests",
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"i/o timeout",
+	"tls handshake timeout",
+	"timeout exceeded",
+	"unexpected eof",
+	"server misbehaving",
+	"service unavailable",
+	"bad gateway",
+	"gateway timeout",
+	"internal server error",
+}
+
+// ErrStreamInterrupted is returned for pull streams which could not be read any further, e.g. after a connection
+// reset, or which ended before their layers were done. It is retryable.
+var ErrStreamInterrupted = errors.New("pull stream interrupted")
+
+// IsRetryable classifies errors of the engine API and of the pull stream (events.PullError/events.LayerError messages)
+// as transient
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrStreamInterrupted) {
+		return true
+	}
+	if IsRetryableMessage(err.Error()) {
+		return true
+	}
+	if errdefs.IsNotFound(err) || errdefs.IsUnauthorized(err) || errdefs.IsPermissionDenied(err) ||
+		errdefs.IsInvalidArgument(err) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errdefs.IsUnavailable(err) || errdefs.IsInternal(err) || errdefs.IsResourceExhausted(err) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// IsRetryableMessage classifies an error message by well-known substrings
+func IsRetryableMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, m := range permanentMessages {
+		if strings.Contains(message, m) {
+			return false
+		}
+	}
+	for _, m := range transientMessages {
+		if strings.Contains(message, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStreamWithRetry parses the pull stream like ParseStream. When the stream reports a retryable error, it emits
+// an *events.Retrying event instead, waits for the backoff and continues with the stream returned by open.
+// firstErr is the error of the initial request, reader is nil in that case. store is the engine's image store, it
+// decides when the layers of a stream ending without a final status are done.
+func ParseStreamWithRetry(
+	ctx context.Context, policy RetryPolicy, store capabilities.ImageStore, reader io.ReadCloser, firstErr error,
+	open func(ctx context.Context) (io.ReadCloser, error),
+) chan events.PullEvent {
+	result := make(chan events.PullEvent)
+	go retryEvents(ctx, policy, store, reader, firstErr, open, result)
+	return result
+}
+
+func retryEvents(
+	ctx context.Context, policy RetryPolicy, store capabilities.ImageStore, reader io.ReadCloser, err error,
+	open func(ctx context.Context) (io.ReadCloser, error), ch chan events.PullEvent,
+) {
+	defer close(ch)
+	send := func(event events.PullEvent) bool {
+		select {
+		case ch <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err == nil {
+			err = forwardAttempt(ctx, policy, store, attempt, reader, send)
+			if err == nil {
+				return
+			}
+		}
+		if !policy.ShouldRetry(attempt, err) {
+			send(&events.PullError{Error: err.Error()})
+			return
+		}
+		delay := policy.Backoff(attempt + 1)
+		if !send(&events.Retrying{
+			Attempt: attempt + 1, MaxAttempts: policy.MaxAttempts, Delay: delay, Reason: err.Error(),
+		}) {
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		reader, err = open(ctx)
+	}
+}
+
+// forwardAttempt forwards the events of one attempt, returns the error if the attempt failed with a retryable
+// error event, which is not forwarded in that case. A stream which can't be read any further, or which ends without
+// a final status before all its layers are done, fails with ErrStreamInterrupted.
+func forwardAttempt(
+	ctx context.Context, policy RetryPolicy, store capabilities.ImageStore, attempt int, reader io.ReadCloser,
+	send func(events.PullEvent) bool,
+) error {
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// a retried attempt reports all layers again
+	layers := state.NewLayerTracker(store)
+	var final, failed bool
+	for event := range ParseStream(attemptCtx, reader) {
+		if layerEvent, ok := event.(events.LayerEvent); ok {
+			layers.Add(layerEvent)
+		}
+		var err error
+		switch event := event.(type) {
+		case *events.PullError:
+			err = errors.New(event.Error)
+		case *events.LayerError:
+			err = errors.New(event.Error)
+		case *events.DecodeError:
+			if len(event.Line) == 0 {
+				// the stream could not be read any further
+				return fmt.Errorf("%w: %v", ErrStreamInterrupted, event.Err)
+			}
+		case events.FinalEvent:
+			final = true
+		}
+		if err != nil {
+			if policy.ShouldRetry(attempt, err) {
+				return err
+			}
+			failed = true
+		}
+		if !send(event) {
+			return nil
+		}
+	}
+	if !final && !failed && !layers.Done() && ctx.Err() == nil {
+		return fmt.Errorf("%w: stream ended before the pull completed", ErrStreamInterrupted)
+	}
+	return nil
+}
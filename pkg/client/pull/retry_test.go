@@ -0,0 +1,161 @@
+package pull
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	require.True(t, IsRetryable(errors.New("toomanyrequests: You have reached your pull rate limit")))
+	require.True(t, IsRetryable(errors.New("read tcp 10.0.0.1:1234: connection reset by peer")))
+	require.True(t, IsRetryable(io.ErrUnexpectedEOF))
+	require.False(t, IsRetryable(errors.New("manifest unknown: manifest unknown")))
+	require.False(t, IsRetryable(errors.New("pull access denied for foo, repository does not exist")))
+	require.False(t, IsRetryable(context.Canceled))
+	require.True(t, IsRetryable(ErrStreamInterrupted))
+	// status codes are only matched by their text, digests may contain them
+	require.False(t, IsRetryable(errors.New("sha256:5030abc: invalid layer")))
+}
+
+func TestParseStreamWithRetryInterrupted(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+	truncated := `{"status":"Pulling from library/alpine","id":"latest"}`
+	succeeding := `{"status":"Pulling from library/alpine","id":"latest"}
+{"status":"Status: Downloaded newer image for alpine:latest"}`
+
+	attempts := 1
+	open := func(ctx context.Context) (io.ReadCloser, error) {
+		attempts++
+		if attempts == 2 {
+			return io.NopCloser(io.MultiReader(strings.NewReader(truncated+"\n"), failingReader{})), nil
+		}
+		return io.NopCloser(strings.NewReader(succeeding)), nil
+	}
+
+	var parsed []events.PullEvent
+	stream := ParseStreamWithRetry(
+		context.Background(), policy, capabilities.ImageStoreClassic, io.NopCloser(strings.NewReader(truncated)), nil,
+		open,
+	)
+	for event := range stream {
+		parsed = append(parsed, event)
+	}
+
+	require.Equal(t, 3, attempts)
+	require.IsType(t, &events.Retrying{}, parsed[1])
+	require.Contains(t, parsed[1].(*events.Retrying).Reason, "ended before the pull completed")
+	require.IsType(t, &events.Retrying{}, parsed[3])
+	require.Contains(t, parsed[3].(*events.Retrying).Reason, "connection reset")
+	require.IsType(t, &events.DownloadedNewerImage{}, parsed[len(parsed)-1])
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read tcp 10.0.0.1:1234: connection reset by peer")
+}
+
+func TestParseStreamWithRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+	failing := `{"status":"Pulling from library/alpine","id":"latest"}
+{"errorDetail":{"message":"toomanyrequests"},"error":"toomanyrequests"}`
+	succeeding := `{"status":"Pulling from library/alpine","id":"latest"}
+{"status":"Status: Image is up to date for alpine:latest"}`
+
+	attempts := 1
+	open := func(ctx context.Context) (io.ReadCloser, error) {
+		attempts++
+		if attempts == 2 {
+			return nil, errors.New("503 Service Unavailable")
+		}
+		return io.NopCloser(strings.NewReader(succeeding)), nil
+	}
+
+	var parsed []events.PullEvent
+	stream := ParseStreamWithRetry(
+		context.Background(), policy, capabilities.ImageStoreClassic, io.NopCloser(strings.NewReader(failing)), nil,
+		open,
+	)
+	for event := range stream {
+		parsed = append(parsed, event)
+	}
+
+	require.Equal(t, 3, attempts)
+	require.Len(t, parsed, 5)
+	require.IsType(t, &events.PullStarted{}, parsed[0])
+	require.IsType(t, &events.Retrying{}, parsed[1])
+	require.Equal(t, 2, parsed[1].(*events.Retrying).Attempt)
+	require.IsType(t, &events.Retrying{}, parsed[2])
+	require.Equal(t, 3, parsed[2].(*events.Retrying).Attempt)
+	require.IsType(t, &events.PullStarted{}, parsed[3])
+	require.IsType(t, &events.UpToDate{}, parsed[4])
+}
+
+func TestParseStreamWithRetryGivesUp(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 2}
+	open := func(ctx context.Context) (io.ReadCloser, error) {
+		return nil, errors.New("503 Service Unavailable")
+	}
+
+	var parsed []events.PullEvent
+	stream := ParseStreamWithRetry(
+		context.Background(), policy, capabilities.ImageStoreClassic, nil, errors.New("connection reset"), open,
+	)
+	for event := range stream {
+		parsed = append(parsed, event)
+	}
+
+	require.Len(t, parsed, 2)
+	require.IsType(t, &events.Retrying{}, parsed[0])
+	require.IsType(t, &events.PullError{}, parsed[1])
+	require.Equal(t, "503 Service Unavailable", parsed[1].(*events.PullError).Error)
+}
+
+func TestParseStreamWithRetryLayersDone(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+	downloaded := `{"status":"Pulling from library/alpine","id":"latest"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"aaaaaaaaaaaa"}
+{"status":"Download complete","progressDetail":{},"id":"aaaaaaaaaaaa"}`
+	extracted := downloaded + `
+{"status":"Pull complete","progressDetail":{},"id":"aaaaaaaaaaaa"}`
+
+	attempts := 1
+	open := func(ctx context.Context) (io.ReadCloser, error) {
+		attempts++
+		return io.NopCloser(strings.NewReader(extracted)), nil
+	}
+	parse := func(store capabilities.ImageStore, input string) []events.PullEvent {
+		var parsed []events.PullEvent
+		stream := ParseStreamWithRetry(
+			context.Background(), policy, store, io.NopCloser(strings.NewReader(input)), nil, open,
+		)
+		for event := range stream {
+			parsed = append(parsed, event)
+		}
+		return parsed
+	}
+
+	// a stream ending without a final status after all layers are done completed
+	parsed := parse(capabilities.ImageStoreClassic, extracted)
+	require.Equal(t, 1, attempts)
+	require.Len(t, parsed, 4)
+	require.IsType(t, &events.PullComplete{}, parsed[3])
+
+	// containerd unpacks after the download, a downloaded layer is done
+	parsed = parse(capabilities.ImageStoreContainerd, downloaded)
+	require.Equal(t, 1, attempts)
+	require.Len(t, parsed, 3)
+
+	// the graph driver still has to extract a downloaded layer
+	parsed = parse(capabilities.ImageStoreClassic, downloaded)
+	require.Equal(t, 2, attempts)
+	require.IsType(t, &events.Retrying{}, parsed[3])
+	require.IsType(t, &events.PullComplete{}, parsed[len(parsed)-1])
+}
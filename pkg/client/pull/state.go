@@ -5,26 +5,30 @@ import (
 
 	"github.com/distribution/reference"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/state"
+	"go.uber.org/zap"
 )
 
+// StateFromStream folds the pull events into pull states.
+// Events which are not valid in the current state are logged and skipped instead of aborting the stream.
+// The transitions depend on the image store of the engine, as the stores report layer progress differently.
 func StateFromStream(
-	ctx context.Context, ref reference.Named, isPodman bool, ch chan events.PullEvent, manifest *v1.Manifest,
-	dig v1.Hash,
+	ctx context.Context, ref reference.Named, store capabilities.ImageStore, ch chan events.PullEvent, manifest *v1.Manifest,
+	dig v1.Hash, logger *zap.SugaredLogger,
 ) chan state.Pull {
 	out := make(chan state.Pull)
 
-	go processEvents(ctx, ref, isPodman, ch, manifest, dig, out)
+	go processEvents(ctx, ref, store, ch, manifest, dig, out, logger)
 
 	return out
 }
 
 func processEvents(
-	ctx context.Context, ref reference.Named, isPodman bool,
+	ctx context.Context, ref reference.Named, store capabilities.ImageStore,
 	ch chan events.PullEvent, manifest *v1.Manifest,
-	dig v1.Hash, out chan state.Pull,
+	dig v1.Hash, out chan state.Pull, logger *zap.SugaredLogger,
 ) {
 	defer close(out)
 	var current state.Pull
@@ -32,46 +36,42 @@ func processEvents(
 	for {
 		select {
 		case <-ctx.Done():
-			goto done
+			return
 		case event, ok := <-ch:
 			if !ok {
 				goto done
 			}
+			if decodeErr, ok := event.(*events.DecodeError); ok {
+				logger.Warnf("skipping undecodable pull event: %s", decodeErr.String())
+				continue
+			}
 			var next state.Pull
 			if current == nil {
-				next, err = state.NewPullState(ref, isPodman, manifest, dig, event)
+				next, err = state.NewPullState(ref, store, manifest, dig, event)
 			} else {
 				next, err = current.Next(event)
 			}
 			if err != nil {
-				panic(err)
+				logger.Warnf("skipping pull event %q: %v", event.String(), err)
+				continue
 			}
 			current = next
-			out <- current
+			select {
+			case out <- current:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
 done:
-	_, pullComplete := current.(*state.PullComplete)
-	_, pullErrored := current.(*state.PullErrored)
-	if !pullComplete && !pullErrored {
-		success := true
-		pulledNewer := false
-		for _, l := range current.Layers() {
-			_, isComplete := l.(*state.LayerPullComplete)
-			_, isAlreadyExists := l.(*state.LayerAlreadyExists)
-			if isComplete {
-				pulledNewer = true
-			}
-			if !isComplete && !isAlreadyExists {
-				success = false
-				break
-			}
-		}
-		if success {
-			out <- &state.PullComplete{
-				PullBase:        current.Base(),
-				ImageDigest:     digest.Digest(dig.String()),
-				DownloadedNewer: pulledNewer,
+	if current == nil {
+		return
+	}
+	if inProgress, ok := current.(*state.PullInProgress); ok {
+		if complete, ok := inProgress.Finish(); ok {
+			select {
+			case out <- complete:
+			case <-ctx.Done():
 			}
 		}
 	}
@@ -7,8 +7,9 @@ import (
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
 )
 
-func NewLayer(isPodman bool, event events.LayerEvent) (Layer, error) {
-	base := layerBase{id: event.LayerId(), isPodman: isPodman}
+func NewLayer(event events.LayerEvent) (Layer, error) {
+	t := now()
+	base := layerBase{id: event.LayerId(), startedAt: t, updatedAt: t}
 	switch event := event.(type) {
 	case *events.PullingFSLayer:
 		return &LayerPullingFSLayer{base}, nil
@@ -44,19 +45,15 @@ func (l *LayerPullingFSLayer) Status() string {
 func (l *LayerPullingFSLayer) Next(event events.LayerEvent) (Layer, error) {
 	switch event := event.(type) {
 	case *events.Waiting:
-		return &LayerWaiting{l.layerBase}, nil
+		return &LayerWaiting{l.touch()}, nil
 	case *events.Downloading:
-		return &LayerDownloading{l.layerBase, event.Progress()}, nil
+		return &LayerDownloading{l.touch(), event.Progress()}, nil
 	case *events.DownloadComplete:
-		if l.isPodman {
-			return &LayerPullComplete{l.layerBase}, nil
-		} else {
-			return &LayerDownloadComplete{l.layerBase}, nil
-		}
+		return &LayerDownloadComplete{l.touch()}, nil
 	case *events.AlreadyExists:
-		return &LayerAlreadyExists{l.layerBase}, nil
+		return &LayerAlreadyExists{l.touch()}, nil
 	case *events.LayerError:
-		return &LayerErrored{l.layerBase, event.Error}, nil
+		return &LayerErrored{l.touch(), event.Error}, nil
 	}
 	return nil, fmt.Errorf("invalid transition (pulling-fs-layer + %T)", event)
 }
@@ -72,17 +69,13 @@ func (l *LayerWaiting) Status() string {
 func (l *LayerWaiting) Next(event events.LayerEvent) (Layer, error) {
 	switch event := event.(type) {
 	case *events.AlreadyExists:
-		return &LayerAlreadyExists{l.layerBase}, nil
+		return &LayerAlreadyExists{l.touch()}, nil
 	case *events.Downloading:
-		return &LayerDownloading{l.layerBase, event.Progress()}, nil
+		return &LayerDownloading{l.touch(), event.Progress()}, nil
 	case *events.DownloadComplete:
-		if l.isPodman {
-			return &LayerPullComplete{l.layerBase}, nil
-		} else {
-			return &LayerDownloadComplete{l.layerBase}, nil
-		}
+		return &LayerDownloadComplete{l.touch()}, nil
 	case *events.LayerError:
-		return &LayerErrored{l.layerBase, event.Error}, nil
+		return &LayerErrored{l.touch(), event.Error}, nil
 	}
 	return nil, fmt.Errorf("invalid transition (waiting + %T)", event)
 }
@@ -103,19 +96,15 @@ func (l *LayerDownloading) Progress() events.Progress {
 func (l *LayerDownloading) Next(event events.LayerEvent) (Layer, error) {
 	switch event := event.(type) {
 	case *events.Downloading:
-		return &LayerDownloading{l.layerBase, event.Progress()}, nil
+		return &LayerDownloading{l.touch(), event.Progress()}, nil
 	case *events.DownloadComplete:
-		if l.isPodman {
-			return &LayerPullComplete{l.layerBase}, nil
-		} else {
-			return &LayerDownloadComplete{l.layerBase}, nil
-		}
+		return &LayerDownloadComplete{l.touch()}, nil
 	case *events.VerifyingChecksum:
-		return &LayerVerifyingChecksum{l.layerBase}, nil
+		return &LayerVerifyingChecksum{l.touch()}, nil
 	case *events.Extracting:
-		return parseLayerExtracting(l.layerBase, event), nil
+		return parseLayerExtracting(l.touch(), event), nil
 	case *events.LayerError:
-		return &LayerErrored{l.layerBase, event.Error}, nil
+		return &LayerErrored{l.touch(), event.Error}, nil
 	}
 	return nil, fmt.Errorf("invalid transition (downloading + %T)", event)
 }
@@ -131,11 +120,11 @@ func (l *LayerVerifyingChecksum) Status() string {
 func (l *LayerVerifyingChecksum) Next(event events.LayerEvent) (Layer, error) {
 	switch event := event.(type) {
 	case *events.DownloadComplete:
-		return &LayerDownloadComplete{l.layerBase}, nil
+		return &LayerDownloadComplete{l.touch()}, nil
 	case *events.Extracting:
-		return parseLayerExtracting(l.layerBase, event), nil
+		return parseLayerExtracting(l.touch(), event), nil
 	case *events.LayerError:
-		return &LayerErrored{l.layerBase, event.Error}, nil
+		return &LayerErrored{l.touch(), event.Error}, nil
 	}
 	return nil, fmt.Errorf("invalid transition (verifying-checksum + %T)", event)
 }
@@ -151,17 +140,13 @@ func (l *LayerDownloadComplete) Status() string {
 func (l *LayerDownloadComplete) Next(event events.LayerEvent) (Layer, error) {
 	switch event := event.(type) {
 	case *events.Extracting:
-		return parseLayerExtracting(l.layerBase, event), nil
+		return parseLayerExtracting(l.touch(), event), nil
 	case *events.LayerError:
-		return &LayerErrored{l.layerBase, event.Error}, nil
+		return &LayerErrored{l.touch(), event.Error}, nil
 	case *events.PullComplete:
-		return &LayerPullComplete{l.layerBase}, nil
+		return &LayerPullComplete{l.touch()}, nil
 	case *events.DownloadComplete:
-		if l.isPodman {
-			return &LayerPullComplete{l.layerBase}, nil
-		} else {
-			return &LayerDownloadComplete{l.layerBase}, nil
-		}
+		return &LayerDownloadComplete{l.touch()}, nil
 	}
 	return nil, fmt.Errorf("invalid transition (download-complete + %T)", event)
 }
@@ -194,11 +179,11 @@ func (l *LayerExtracting) Duration() *time.Duration {
 func (l *LayerExtracting) Next(event events.LayerEvent) (Layer, error) {
 	switch event := event.(type) {
 	case *events.Extracting:
-		return parseLayerExtracting(l.layerBase, event), nil
+		return parseLayerExtracting(l.touch(), event), nil
 	case *events.PullComplete:
-		return &LayerPullComplete{l.layerBase}, nil
+		return &LayerPullComplete{l.touch()}, nil
 	case *events.LayerError:
-		return &LayerErrored{l.layerBase, event.Error}, nil
+		return &LayerErrored{l.touch(), event.Error}, nil
 	}
 	return nil, fmt.Errorf("invalid transition (extracting + %T)", event)
 }
@@ -226,13 +211,13 @@ func (l *LayerAlreadyExists) Status() string {
 func (l *LayerAlreadyExists) Next(event events.LayerEvent) (Layer, error) {
 	switch event := event.(type) {
 	case *events.Extracting:
-		return parseLayerExtracting(l.layerBase, event), nil
+		return parseLayerExtracting(l.touch(), event), nil
 	case *events.LayerError:
-		return &LayerErrored{l.layerBase, event.Error}, nil
+		return &LayerErrored{l.touch(), event.Error}, nil
 	case *events.PullComplete:
-		return &LayerPullComplete{l.layerBase}, nil
+		return &LayerPullComplete{l.touch()}, nil
 	case *events.AlreadyExists:
-		return &LayerAlreadyExists{l.layerBase}, nil
+		return &LayerAlreadyExists{l.touch()}, nil
 	}
 	return nil, fmt.Errorf("invalid transition (already-exists + %T)", event)
 }
@@ -246,11 +231,5 @@ func (l *LayerPullComplete) Status() string {
 }
 
 func (l *LayerPullComplete) Next(event events.LayerEvent) (Layer, error) {
-	switch event.(type) {
-	case *events.DownloadComplete:
-		if l.isPodman {
-			return &LayerPullComplete{l.layerBase}, nil
-		}
-	}
 	return nil, fmt.Errorf("already completed, tried %T on layer-pull-complete", event)
 }
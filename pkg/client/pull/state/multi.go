@@ -0,0 +1,154 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/distribution/reference"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Multi is the combined state of several concurrent pulls.
+// Layers shared between images are only counted once.
+type Multi struct {
+	refs    []reference.Named
+	pulls   []Pull
+	samples []progressSample
+}
+
+func NewMultiState(refs []reference.Named) *Multi {
+	return &Multi{
+		refs:  refs,
+		pulls: make([]Pull, len(refs)),
+	}
+}
+
+// With returns a copy of the state with the pull at index replaced
+func (m *Multi) With(index int, pull Pull) *Multi {
+	next := &Multi{
+		refs:  m.refs,
+		pulls: make([]Pull, len(m.pulls)),
+	}
+	copy(next.pulls, m.pulls)
+	next.pulls[index] = pull
+	current, _ := next.bytes()
+	next.samples = appendSample(m.samples, progressSample{at: now(), bytes: current})
+	return next
+}
+
+func (m *Multi) Refs() []reference.Named {
+	return m.refs
+}
+
+// Pulls returns the pull states in request order, entries are nil for pulls that did not start yet
+func (m *Multi) Pulls() []Pull {
+	return m.pulls
+}
+
+func (m *Multi) Pull(index int) Pull {
+	return m.pulls[index]
+}
+
+func (m *Multi) Status() string {
+	var started, complete, errored int
+	for _, p := range m.pulls {
+		switch p.(type) {
+		case nil:
+			continue
+		case *PullComplete:
+			complete++
+		case *PullErrored:
+			errored++
+		}
+		started++
+	}
+	status := fmt.Sprintf("Pulled %d/%d images", complete, len(m.pulls))
+	if queued := len(m.pulls) - started; queued > 0 {
+		status += fmt.Sprintf(", %d queued", queued)
+	}
+	if errored > 0 {
+		status += fmt.Sprintf(", %d failed", errored)
+	}
+	return status
+}
+
+// Layers returns the layers of all started pulls, de-duplicated by digest.
+// For shared layers, the state which progressed furthest is returned.
+func (m *Multi) Layers() []Layer {
+	var layers []Layer
+	for _, entry := range m.uniqueLayers() {
+		if entry.layer != nil {
+			layers = append(layers, entry.layer)
+		}
+	}
+	return layers
+}
+
+func (m *Multi) Progress() Progress {
+	current, total := m.bytes()
+	return estimate(current, total, m.samples)
+}
+
+func (m *Multi) UpdatedAt() time.Time {
+	if len(m.samples) == 0 {
+		return time.Time{}
+	}
+	return m.samples[len(m.samples)-1].at
+}
+
+type multiLayer struct {
+	layer   Layer
+	current int64
+	total   int64
+	counted bool
+}
+
+func (m *Multi) uniqueLayers() []multiLayer {
+	var order []v1.Hash
+	byDigest := map[v1.Hash]multiLayer{}
+	for _, p := range m.pulls {
+		if p == nil || p.Manifest() == nil {
+			continue
+		}
+		base := p.Base()
+		for _, desc := range p.Manifest().Layers {
+			layer, _ := base.layerFor(desc)
+			current, total, counted := layerBytes(layer, desc)
+			entry := multiLayer{layer, current, total, counted}
+			existing, found := byDigest[desc.Digest]
+			if !found {
+				order = append(order, desc.Digest)
+			}
+			if !found || isFurther(entry, existing) {
+				byDigest[desc.Digest] = entry
+			}
+		}
+	}
+	result := make([]multiLayer, 0, len(order))
+	for _, dig := range order {
+		result = append(result, byDigest[dig])
+	}
+	return result
+}
+
+// isFurther reports whether a is more advanced than b. Already existing layers win, as they don't need a download.
+func isFurther(a, b multiLayer) bool {
+	if a.counted != b.counted {
+		return !a.counted
+	}
+	if b.layer == nil {
+		return a.layer != nil
+	}
+	return a.current > b.current
+}
+
+func (m *Multi) bytes() (int64, int64) {
+	var current, total int64
+	for _, entry := range m.uniqueLayers() {
+		if entry.counted {
+			current += entry.current
+			total += entry.total
+		}
+	}
+	return current, total
+}
@@ -0,0 +1,130 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/go-units"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// throughputWindow is the time span the rolling throughput estimate is computed over
+const throughputWindow = 10 * time.Second
+
+// Progress is the aggregated download progress of a pull.
+// Layers which already exist locally are not counted.
+type Progress struct {
+	Current int64
+	Total   int64
+	// BytesPerSecond is a rolling throughput estimate, zero if unknown
+	BytesPerSecond float64
+	// Remaining is the estimated time until all layers are downloaded, zero if unknown
+	Remaining time.Duration
+}
+
+func (p Progress) Fraction() float64 {
+	if p.Total == 0 {
+		return 0
+	}
+	return float64(p.Current) / float64(p.Total)
+}
+
+func (p Progress) HasETA() bool {
+	return p.Remaining > 0
+}
+
+func (p Progress) String() string {
+	result := fmt.Sprintf("%s/%s", units.HumanSize(float64(p.Current)), units.HumanSize(float64(p.Total)))
+	if p.BytesPerSecond > 0 {
+		result += fmt.Sprintf(" (%s/s)", units.HumanSize(p.BytesPerSecond))
+	}
+	if p.HasETA() {
+		result += fmt.Sprintf(" ETA %s", p.Remaining.Round(time.Second).String())
+	}
+	return result
+}
+
+type progressSample struct {
+	at    time.Time
+	bytes int64
+}
+
+func (p *PullBase) Progress() Progress {
+	current, total := p.bytes()
+	return estimate(current, total, p.samples)
+}
+
+// estimate derives throughput and remaining time from the samples
+func estimate(current, total int64, samples []progressSample) Progress {
+	progress := Progress{Current: current, Total: total}
+	if len(samples) < 2 {
+		return progress
+	}
+	first := samples[0]
+	last := samples[len(samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 || last.bytes <= first.bytes {
+		return progress
+	}
+	progress.BytesPerSecond = float64(last.bytes-first.bytes) / elapsed
+	if remaining := total - current; remaining > 0 {
+		progress.Remaining = time.Duration(float64(remaining) / progress.BytesPerSecond * float64(time.Second))
+	}
+	return progress
+}
+
+// bytes sums up downloaded and total bytes of all layers, using the manifest layer sizes where the daemon didn't
+// report a total
+func (p *PullBase) bytes() (int64, int64) {
+	if p.manifest == nil {
+		return 0, 0
+	}
+	var current, total int64
+	for _, desc := range p.manifest.Layers {
+		layer, _ := p.layerFor(desc)
+		c, t, counted := layerBytes(layer, desc)
+		if counted {
+			current += c
+			total += t
+		}
+	}
+	return current, total
+}
+
+func layerBytes(layer Layer, desc v1.Descriptor) (int64, int64, bool) {
+	switch layer := layer.(type) {
+	case nil, *LayerPullingFSLayer, *LayerWaiting, *LayerErrored:
+		return 0, desc.Size, true
+	case *LayerAlreadyExists:
+		return 0, 0, false
+	case *LayerDownloading:
+		total := int64(layer.progress.Total)
+		if total == 0 {
+			total = desc.Size
+		}
+		return min(int64(layer.progress.Current), total), total, true
+	default: // verifying, download complete, extracting, pull complete
+		return desc.Size, desc.Size, true
+	}
+}
+
+// withSample records the current download state at the given time
+func (p *PullBase) withSample(at time.Time) PullBase {
+	next := *p
+	next.updatedAt = at
+	current, _ := p.bytes()
+	next.samples = appendSample(p.samples, progressSample{at: at, bytes: current})
+	return next
+}
+
+// appendSample returns a copy of samples with sample appended. Samples outside the throughput window are dropped,
+// except the newest one of them, which anchors the estimate.
+func appendSample(samples []progressSample, sample progressSample) []progressSample {
+	start := 0
+	for start < len(samples)-1 && sample.at.Sub(samples[start+1].at) >= throughputWindow {
+		start++
+	}
+	result := make([]progressSample, 0, len(samples)-start+1)
+	result = append(result, samples[start:]...)
+	return append(result, sample)
+}
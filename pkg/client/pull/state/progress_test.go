@@ -0,0 +1,95 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/distribution/reference"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/base"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+	"github.com/stretchr/testify/require"
+)
+
+func parseEvent(t *testing.T, id, status string, current, total int) events.PullEvent {
+	raw := base.PullProgressEvent{ID: id, Status: status}
+	raw.ProgressDetail.Current = current
+	raw.ProgressDetail.Total = total
+	event, err := events.Parse(raw)
+	require.NoError(t, err)
+	return event
+}
+
+func TestPullProgress(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	layerA := v1.Hash{Algorithm: "sha256", Hex: "aaaaaaaaaaaa0000000000000000000000000000000000000000000000000000"}
+	layerB := v1.Hash{Algorithm: "sha256", Hex: "bbbbbbbbbbbb0000000000000000000000000000000000000000000000000000"}
+	layerC := v1.Hash{Algorithm: "sha256", Hex: "cccccccccccc0000000000000000000000000000000000000000000000000000"}
+	manifest := &v1.Manifest{Layers: []v1.Descriptor{
+		{Digest: layerA, Size: 1000},
+		{Digest: layerB, Size: 3000},
+		{Digest: layerC, Size: 500},
+	}}
+	ref, err := reference.ParseNormalizedNamed("alpine:latest")
+	require.NoError(t, err)
+
+	current, err := NewPullState(ref, capabilities.ImageStoreClassic, manifest, v1.Hash{}, &events.PullStarted{})
+	require.NoError(t, err)
+	require.Equal(t, clock, current.StartedAt())
+
+	apply := func(event events.PullEvent) {
+		clock = clock.Add(time.Second)
+		current, err = current.Next(event)
+		require.NoError(t, err)
+		require.Equal(t, clock, current.UpdatedAt())
+	}
+	apply(parseEvent(t, "aaaaaaaaaaaa", events.PullingFSLayerStatus, 0, 0))
+	apply(parseEvent(t, "bbbbbbbbbbbb", events.PullingFSLayerStatus, 0, 0))
+	apply(parseEvent(t, "cccccccccccc", events.AlreadyExistsStatus, 0, 0))
+
+	progress := current.Progress()
+	require.EqualValues(t, 0, progress.Current)
+	require.EqualValues(t, 4000, progress.Total)
+	require.Zero(t, progress.BytesPerSecond)
+
+	apply(parseEvent(t, "aaaaaaaaaaaa", events.DownloadingStatus, 500, 1000))
+	apply(parseEvent(t, "aaaaaaaaaaaa", events.DownloadCompleteStatus, 0, 0))
+
+	progress = current.Progress()
+	require.EqualValues(t, 1000, progress.Current)
+	require.EqualValues(t, 4000, progress.Total)
+	require.InDelta(t, 0.25, progress.Fraction(), 0.0001)
+	require.Greater(t, progress.BytesPerSecond, 0.0)
+	require.True(t, progress.HasETA())
+	require.Equal(t, clock, current.Layer("aaaaaaaaaaaa").UpdatedAt())
+}
+
+func TestMultiProgressDeduplicatesLayers(t *testing.T) {
+	shared := v1.Descriptor{Digest: v1.Hash{Algorithm: "sha256", Hex: "aaaaaaaaaaaa0000000000000000000000000000000000000000000000000000"}, Size: 1000}
+	onlyA := v1.Descriptor{Digest: v1.Hash{Algorithm: "sha256", Hex: "bbbbbbbbbbbb0000000000000000000000000000000000000000000000000000"}, Size: 200}
+	onlyB := v1.Descriptor{Digest: v1.Hash{Algorithm: "sha256", Hex: "cccccccccccc0000000000000000000000000000000000000000000000000000"}, Size: 300}
+	refA, err := reference.ParseNormalizedNamed("a:latest")
+	require.NoError(t, err)
+	refB, err := reference.ParseNormalizedNamed("b:latest")
+	require.NoError(t, err)
+
+	pullA, err := NewPullState(refA, capabilities.ImageStoreClassic, &v1.Manifest{Layers: []v1.Descriptor{shared, onlyA}}, v1.Hash{}, &events.PullStarted{})
+	require.NoError(t, err)
+	pullA, err = pullA.Next(parseEvent(t, "aaaaaaaaaaaa", events.PullingFSLayerStatus, 0, 0))
+	require.NoError(t, err)
+	pullA, err = pullA.Next(parseEvent(t, "aaaaaaaaaaaa", events.DownloadingStatus, 400, 1000))
+	require.NoError(t, err)
+	pullB, err := NewPullState(refB, capabilities.ImageStoreClassic, &v1.Manifest{Layers: []v1.Descriptor{shared, onlyB}}, v1.Hash{}, &events.PullStarted{})
+	require.NoError(t, err)
+
+	multi := NewMultiState([]reference.Named{refA, refB}).With(0, pullA).With(1, pullB)
+	progress := multi.Progress()
+	require.EqualValues(t, 400, progress.Current)
+	require.EqualValues(t, 1500, progress.Total)
+	require.Len(t, multi.Layers(), 1)
+	require.Equal(t, "Pulled 0/2 images", multi.Status())
+}
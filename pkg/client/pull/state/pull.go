@@ -7,44 +7,67 @@ import (
 	"github.com/distribution/reference"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
 )
 
 type PullInProgress struct {
 	PullBase
 	digest *digest.Digest
+	retry  *events.Retrying
 }
 
 func (p *PullInProgress) Status() string {
-	if p.digest == nil {
-		return "Pulling"
+	status := "Pulling"
+	if p.digest != nil {
+		status = "Finishing"
 	}
-	return "Finishing"
+	if p.retry != nil {
+		status += fmt.Sprintf(" (attempt %d/%d)", p.retry.Attempt, p.retry.MaxAttempts)
+	}
+	return status
 }
 
-func NewPullState(ref reference.Named, isPodman bool, manifest *v1.Manifest, dig v1.Hash, event events.PullEvent) (
-	Pull, error,
-) {
+func NewPullState(
+	ref reference.Named, store capabilities.ImageStore, manifest *v1.Manifest, dig v1.Hash, event events.PullEvent,
+) (Pull, error) {
 	base := PullBase{
 		ref:      ref,
 		manifest: manifest,
 		digest:   dig,
 		layers:   make(map[string]Layer),
-		isPodman: isPodman,
+		store:    store,
+	}
+	if event, ok := event.(events.LayerEvent); ok {
+		layer, err := NewLayer(event)
+		if err != nil {
+			return nil, err
+		}
+		base.layers[event.LayerId()] = layer
 	}
+	t := now()
+	base.startedAt = t
+	base = base.withSample(t)
+
 	switch event := event.(type) {
-	case *events.PullStarted:
+	case *events.PullStarted, events.LayerEvent:
 		return &PullInProgress{
 			PullBase: base,
 		}, nil
-	case events.LayerEvent:
-		var err error
-		base.layers[event.LayerId()], err = NewLayer(isPodman, event)
-		if err != nil {
-			return nil, err
-		}
+	case *events.Retrying:
 		return &PullInProgress{
 			PullBase: base,
+			retry:    event,
+		}, nil
+	case *events.PullError:
+		return &PullErrored{
+			PullBase: base,
+			error:    event.Error,
+		}, nil
+	case *events.Skipped:
+		return &PullComplete{
+			PullBase:    base,
+			ImageDigest: event.Digest,
 		}, nil
 	}
 	return nil, fmt.Errorf("invalid initial event (%T)", event)
@@ -56,14 +79,13 @@ func (p *PullInProgress) Next(event events.PullEvent) (Pull, error) {
 		layers = maps.Clone(p.layers)
 		layer, found := layers[le.LayerId()]
 		if found {
-			found = true
-			newL, err := layer.Next(le)
+			newL, err := tableFor(p.store).next(layer, le)
 			if err != nil {
 				return nil, err
 			}
 			layers[layer.Id()] = newL
 		} else {
-			layer, err := NewLayer(p.isPodman, le)
+			layer, err := NewLayer(le)
 			if err != nil {
 				return nil, err
 			}
@@ -71,31 +93,33 @@ func (p *PullInProgress) Next(event events.PullEvent) (Pull, error) {
 		}
 	}
 
+	if _, ok := event.(*events.UnknownEvent); ok {
+		// unknown events carry no information the state machine could use
+		return p, nil
+	}
+	base := p.PullBase
+	base.layers = layers
+	base = base.withSample(now())
+
 	var result Pull
 	switch event := event.(type) {
-	case events.LayerEvent:
+	case events.LayerEvent, *events.PullStarted:
 		result = &PullInProgress{
-			PullBase: PullBase{
-				ref:      p.ref,
-				layers:   layers,
-				manifest: p.manifest,
-				isPodman: p.isPodman,
-			},
-			digest: p.digest,
-		}
-	case *events.PullStarted:
-		result = &PullInProgress{
-			PullBase: p.PullBase,
+			PullBase: base,
 			digest:   p.digest,
+			retry:    p.retry,
 		}
 	case *events.Digest:
 		result = &PullInProgress{
-			p.PullBase,
-			&event.Digest,
+			PullBase: base,
+			digest:   &event.Digest,
+			retry:    p.retry,
 		}
+	case *events.Retrying:
+		result = p.restart(event)
 	case *events.PullError:
 		result = &PullErrored{
-			PullBase: p.PullBase,
+			PullBase: base,
 			error:    event.Error,
 		}
 	case *events.DownloadedNewerImage:
@@ -103,7 +127,7 @@ func (p *PullInProgress) Next(event events.PullEvent) (Pull, error) {
 			return nil, fmt.Errorf("cannot complete pull: no digest event received")
 		}
 		result = &PullComplete{
-			PullBase:        p.PullBase,
+			PullBase:        base,
 			ImageDigest:     *p.digest,
 			DownloadedNewer: true,
 		}
@@ -112,15 +136,43 @@ func (p *PullInProgress) Next(event events.PullEvent) (Pull, error) {
 			return nil, fmt.Errorf("cannot complete pull: no digest event received")
 		}
 		result = &PullComplete{
-			PullBase:        p.PullBase,
+			PullBase:        base,
 			ImageDigest:     *p.digest,
 			DownloadedNewer: false,
 		}
+	default:
+		return nil, fmt.Errorf("unsupported event (%T)", event)
 	}
 
 	return result, nil
 }
 
+// Finish completes a pull whose stream ended without a final event, if all layers are done. The image digest is
+// the one reported by the engine, empty if it reported none.
+func (p *PullInProgress) Finish() (*PullComplete, bool) {
+	done, pulled := p.LayersDone()
+	if !done {
+		return nil, false
+	}
+	result := &PullComplete{PullBase: p.PullBase, DownloadedNewer: pulled}
+	if p.digest != nil {
+		result.ImageDigest = *p.digest
+	}
+	return result, true
+}
+
+// restart discards the layer states of a failed attempt, the retried pull reports all layers again
+func (p *PullBase) restart(retry *events.Retrying) *PullInProgress {
+	base := *p
+	base.layers = make(map[string]Layer)
+	base.samples = nil
+	base = base.withSample(now())
+	return &PullInProgress{
+		PullBase: base,
+		retry:    retry,
+	}
+}
+
 type PullErrored struct {
 	PullBase
 	error string
@@ -130,12 +182,21 @@ func (p *PullErrored) Status() string {
 	return fmt.Sprintf("Error: %s", p.error)
 }
 
-func (p *PullErrored) Next(events.PullEvent) (Pull, error) {
+// Message returns the error reported by the engine
+func (p *PullErrored) Message() string {
+	return p.error
+}
+
+func (p *PullErrored) Next(event events.PullEvent) (Pull, error) {
+	if retry, ok := event.(*events.Retrying); ok {
+		return p.restart(retry), nil
+	}
 	return nil, fmt.Errorf("pull errored: %s", p.error)
 }
 
 type PullComplete struct {
 	PullBase
+	// ImageDigest is the registry digest of the pulled manifest, unlike Digest it is not the local image id
 	ImageDigest     digest.Digest
 	DownloadedNewer bool
 }
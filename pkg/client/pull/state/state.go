@@ -1,9 +1,12 @@
 package state
 
 import (
+	"time"
+
 	"github.com/distribution/reference"
 	"github.com/google/go-containerregistry/pkg/v1"
 	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
 )
 
@@ -16,12 +19,20 @@ type Pull interface {
 	Next(event events.PullEvent) (Pull, error)
 	Status() string
 	Base() PullBase
+	// Progress aggregates the download progress of all layers
+	Progress() Progress
+	StartedAt() time.Time
+	// UpdatedAt is the time of the last state transition
+	UpdatedAt() time.Time
 }
 
 type Layer interface {
 	Id() string
 	Status() string
 	Next(event events.LayerEvent) (Layer, error)
+	StartedAt() time.Time
+	// UpdatedAt is the time of the last state transition
+	UpdatedAt() time.Time
 }
 
 type PullBase struct {
@@ -29,7 +40,11 @@ type PullBase struct {
 	layers   map[string]Layer
 	manifest *v1.Manifest
 	digest   v1.Hash
-	isPodman bool
+	store    capabilities.ImageStore
+
+	startedAt time.Time
+	updatedAt time.Time
+	samples   []progressSample
 }
 
 func (p *PullBase) Base() PullBase {
@@ -44,33 +59,90 @@ func (p *PullBase) Manifest() *v1.Manifest {
 	return p.manifest
 }
 
+// Digest is the local image id, as computed before the pull
 func (p *PullBase) Digest() digest.Digest {
 	return digest.Digest(p.digest.String())
 }
 
 func (p *PullBase) Layers() []Layer {
+	if p.manifest == nil {
+		return nil
+	}
 	layers := make([]Layer, 0, len(p.layers))
 	for _, l := range p.manifest.Layers {
-		for i := 1; i <= len(l.Digest.Hex); i++ {
-			layer, ok := p.layers[l.Digest.Hex[:i]]
-			if ok {
-				layers = append(layers, layer)
-				break
-			}
+		if layer, ok := p.layerFor(l); ok {
+			layers = append(layers, layer)
 		}
 	}
 	return layers
 }
 
+// layerFor finds the layer state of a manifest layer, the daemon only reports a (short) digest prefix as id
+func (p *PullBase) layerFor(desc v1.Descriptor) (Layer, bool) {
+	for i := 1; i <= len(desc.Digest.Hex); i++ {
+		layer, ok := p.layers[desc.Digest.Hex[:i]]
+		if ok {
+			return layer, true
+		}
+	}
+	return nil, false
+}
+
+// Store is the image store the events are interpreted for
+func (p *PullBase) Store() capabilities.ImageStore {
+	return p.store
+}
+
+// LayersDone reports whether all layers reached a final state, and whether any of them was pulled
+func (p *PullBase) LayersDone() (done bool, pulled bool) {
+	t := tableFor(p.store)
+	for _, l := range p.Layers() {
+		layerDone, layerPulled := t.done(l)
+		if !layerDone {
+			return false, false
+		}
+		pulled = pulled || layerPulled
+	}
+	return true, pulled
+}
+
+func (p *PullBase) StartedAt() time.Time {
+	return p.startedAt
+}
+
+func (p *PullBase) UpdatedAt() time.Time {
+	return p.updatedAt
+}
+
 func (p *PullBase) Layer(id string) Layer {
 	return p.layers[id]
 }
 
 type layerBase struct {
-	id       string
-	isPodman bool
+	id string
+
+	startedAt time.Time
+	updatedAt time.Time
 }
 
 func (l *layerBase) Id() string {
 	return l.id
 }
+
+func (l *layerBase) StartedAt() time.Time {
+	return l.startedAt
+}
+
+func (l *layerBase) UpdatedAt() time.Time {
+	return l.updatedAt
+}
+
+// touch returns a copy of the layer base with the transition time set to now
+func (l *layerBase) touch() layerBase {
+	next := *l
+	next.updatedAt = now()
+	return next
+}
+
+// now is replaceable for tests
+var now = time.Now
@@ -0,0 +1,131 @@
+package state
+
+import (
+	"reflect"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+)
+
+// transition is a layer state and an event it receives
+type transition struct {
+	from  reflect.Type
+	event reflect.Type
+}
+
+func on[S Layer, E events.LayerEvent]() transition {
+	return transition{reflect.TypeFor[S](), reflect.TypeFor[E]()}
+}
+
+// table holds the behavior which differs between image stores, the shared transitions are implemented by the layer
+// states themselves
+type table struct {
+	// transitions take precedence over the shared transitions
+	transitions map[transition]func(base layerBase) Layer
+	// final are the layer states a pull may end with besides pull complete and already exists
+	final []reflect.Type
+}
+
+func pullComplete(base layerBase) Layer {
+	return &LayerPullComplete{base}
+}
+
+func downloadComplete(base layerBase) Layer {
+	return &LayerDownloadComplete{base}
+}
+
+var tables = map[capabilities.ImageStore]table{
+	// the graph driver extracts each layer after its download and reports it with the shared transitions
+	capabilities.ImageStoreClassic: {},
+	// containerd unpacks the image as a whole after fetching all blobs, so layers may end with download complete.
+	// Finished blobs can be reported again until the unpack completes.
+	capabilities.ImageStoreContainerd: {
+		transitions: map[transition]func(base layerBase) Layer{
+			on[*LayerPullComplete, *events.DownloadComplete]():  pullComplete,
+			on[*LayerDownloadComplete, *events.AlreadyExists](): downloadComplete,
+		},
+		final: []reflect.Type{reflect.TypeFor[*LayerDownloadComplete]()},
+	},
+	// podman extracts while downloading, download complete finishes a layer and is repeated after completion
+	capabilities.ImageStorePodman: {
+		transitions: map[transition]func(base layerBase) Layer{
+			on[*LayerPullingFSLayer, *events.DownloadComplete]():   pullComplete,
+			on[*LayerWaiting, *events.DownloadComplete]():          pullComplete,
+			on[*LayerDownloading, *events.DownloadComplete]():      pullComplete,
+			on[*LayerDownloadComplete, *events.DownloadComplete](): pullComplete,
+			on[*LayerPullComplete, *events.DownloadComplete]():     pullComplete,
+		},
+	},
+}
+
+// tableFor returns the table of store, unknown stores use the classic transitions
+func tableFor(store capabilities.ImageStore) table {
+	if t, ok := tables[store]; ok {
+		return t
+	}
+	return tables[capabilities.ImageStoreClassic]
+}
+
+// next applies event to layer, preferring the store-specific transitions
+func (t table) next(layer Layer, event events.LayerEvent) (Layer, error) {
+	if create, ok := t.transitions[transition{reflect.TypeOf(layer), reflect.TypeOf(event)}]; ok {
+		if base, ok := layer.(interface{ touch() layerBase }); ok {
+			return create(base.touch()), nil
+		}
+	}
+	return layer.Next(event)
+}
+
+// done reports whether layer is in a final state, and whether it was pulled (as opposed to already existing)
+func (t table) done(layer Layer) (done bool, pulled bool) {
+	switch layer.(type) {
+	case *LayerPullComplete:
+		return true, true
+	case *LayerAlreadyExists:
+		return true, false
+	}
+	for _, final := range t.final {
+		if reflect.TypeOf(layer) == final {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// LayerTracker follows the layer states of a pull stream without its manifest, e.g. to tell a stream which ended
+// after all layers were done from an interrupted one
+type LayerTracker struct {
+	table  table
+	layers map[string]Layer
+}
+
+func NewLayerTracker(store capabilities.ImageStore) *LayerTracker {
+	return &LayerTracker{table: tableFor(store), layers: make(map[string]Layer)}
+}
+
+// Add applies event to its layer, events which are invalid in the layer's state are ignored
+func (t *LayerTracker) Add(event events.LayerEvent) {
+	var next Layer
+	var err error
+	if layer, found := t.layers[event.LayerId()]; found {
+		next, err = t.table.next(layer, event)
+	} else {
+		next, err = NewLayer(event)
+	}
+	if err == nil {
+		t.layers[event.LayerId()] = next
+	}
+}
+
+// Done reports whether layers were reported and all of them are in a final state
+func (t *LayerTracker) Done() bool {
+	if len(t.layers) == 0 {
+		return false
+	}
+	for _, layer := range t.layers {
+		if done, _ := t.table.done(layer); !done {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/distribution/reference"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/opencontainers/go-digest"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreTransitions(t *testing.T) {
+	layer := v1.Hash{Algorithm: "sha256", Hex: "aaaaaaaaaaaa0000000000000000000000000000000000000000000000000000"}
+	manifest := &v1.Manifest{Layers: []v1.Descriptor{{Digest: layer, Size: 1000}}}
+	ref, err := reference.ParseNormalizedNamed("alpine:latest")
+	require.NoError(t, err)
+
+	pull := func(store capabilities.ImageStore, statuses ...string) (Pull, error) {
+		current, err := NewPullState(ref, store, manifest, v1.Hash{}, &events.PullStarted{})
+		require.NoError(t, err)
+		for _, status := range statuses {
+			current, err = current.Next(parseEvent(t, "aaaaaaaaaaaa", status, 0, 0))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return current, nil
+	}
+
+	current, err := pull(capabilities.ImageStorePodman, events.PullingFSLayerStatus, events.DownloadCompleteStatus)
+	require.NoError(t, err)
+	require.IsType(t, &LayerPullComplete{}, current.Layer("aaaaaaaaaaaa"))
+	_, err = pull(
+		capabilities.ImageStorePodman,
+		events.PullingFSLayerStatus, events.DownloadCompleteStatus, events.DownloadCompleteStatus,
+	)
+	require.NoError(t, err)
+
+	current, err = pull(capabilities.ImageStoreClassic, events.PullingFSLayerStatus, events.DownloadCompleteStatus)
+	require.NoError(t, err)
+	require.IsType(t, &LayerDownloadComplete{}, current.Layer("aaaaaaaaaaaa"))
+	base := current.Base()
+	done, _ := base.LayersDone()
+	require.False(t, done)
+	_, err = pull(
+		capabilities.ImageStoreClassic,
+		events.PullingFSLayerStatus, events.DownloadCompleteStatus, events.PullCompleteStatus,
+		events.DownloadCompleteStatus,
+	)
+	require.Error(t, err)
+
+	// containerd reports the unpack for the whole image, a downloaded layer is done
+	current, err = pull(capabilities.ImageStoreContainerd, events.PullingFSLayerStatus, events.DownloadCompleteStatus)
+	require.NoError(t, err)
+	base = current.Base()
+	done, pulled := base.LayersDone()
+	require.True(t, done)
+	require.True(t, pulled)
+	current, err = pull(capabilities.ImageStoreContainerd, events.ExistsStatus)
+	require.NoError(t, err)
+	require.IsType(t, &LayerAlreadyExists{}, current.Layer("aaaaaaaaaaaa"))
+	base = current.Base()
+	done, pulled = base.LayersDone()
+	require.True(t, done)
+	require.False(t, pulled)
+}
+
+func TestFinishKeepsRegistryDigest(t *testing.T) {
+	layer := v1.Hash{Algorithm: "sha256", Hex: "aaaaaaaaaaaa0000000000000000000000000000000000000000000000000000"}
+	manifest := &v1.Manifest{Layers: []v1.Descriptor{{Digest: layer, Size: 1000}}}
+	imageID := v1.Hash{Algorithm: "sha256", Hex: "1111111111111111111111111111111111111111111111111111111111111111"}
+	registryDigest := digest.Digest("sha256:2222222222222222222222222222222222222222222222222222222222222222")
+	ref, err := reference.ParseNormalizedNamed("alpine:latest")
+	require.NoError(t, err)
+
+	current, err := NewPullState(ref, capabilities.ImageStoreClassic, manifest, imageID, &events.PullStarted{})
+	require.NoError(t, err)
+	for _, status := range []string{
+		events.PullingFSLayerStatus, events.DownloadCompleteStatus, events.PullCompleteStatus,
+	} {
+		current, err = current.Next(parseEvent(t, "aaaaaaaaaaaa", status, 0, 0))
+		require.NoError(t, err)
+	}
+
+	complete, ok := current.(*PullInProgress).Finish()
+	require.True(t, ok)
+	require.Empty(t, complete.ImageDigest)
+	require.Equal(t, imageID.String(), complete.Digest().String())
+
+	current, err = current.Next(&events.Digest{Digest: registryDigest})
+	require.NoError(t, err)
+	complete, ok = current.(*PullInProgress).Finish()
+	require.True(t, ok)
+	require.Equal(t, registryDigest, complete.ImageDigest)
+	require.True(t, complete.DownloadedNewer)
+}
@@ -0,0 +1,28 @@
+package stream
+
+import (
+	"context"
+	"io"
+
+	"go.uber.org/zap"
+)
+
+// Demux copies a multiplexed stream to stdout and stderr until it ends, like stdcopy.StdCopy.
+// Cancelling ctx stops the copy and closes reader if it is an io.Closer. Otherwise a blocked read is abandoned,
+// its data is discarded.
+func Demux(ctx context.Context, reader io.Reader, stdout, stderr io.Writer) error {
+	closer, _ := reader.(io.Closer)
+	m := NewMultiplexedStream(ctx, reader, closer, nil, true, zap.NewNop().Sugar(), WithOutput(stdout, stderr))
+	select {
+	case <-m.Done():
+	case <-ctx.Done():
+		if closer != nil {
+			<-m.Done()
+		}
+		return ctx.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.Err()
+}
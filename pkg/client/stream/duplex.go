@@ -0,0 +1,74 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"go.uber.org/zap"
+)
+
+// DefaultDetachKeys is the detach sequence of the engines (ctrl-p,ctrl-q)
+var DefaultDetachKeys = []byte{0x10, 0x11}
+
+// Control holds the engine operations of an attached stream
+type Control struct {
+	Resize func(ctx context.Context, height, width uint) error
+	// DetachKeys is the sequence the engine detaches on, DefaultDetachKeys if empty
+	DetachKeys []byte
+}
+
+// Duplex is a full-duplex stream attached to a container or exec: writes go to stdin, stdout and stderr are read
+// from Messages
+type Duplex struct {
+	*MultiplexedStream
+	conn    net.Conn
+	control Control
+}
+
+// NewDuplexStream reads output from reader (the buffered reader of conn) and writes input to conn.
+// TTY output is delivered in chunks as it arrives, so prompts without a trailing newline are not held back.
+func NewDuplexStream(
+	ctx context.Context,
+	conn net.Conn,
+	reader io.Reader,
+	multiplex bool,
+	control Control,
+	logger *zap.SugaredLogger,
+) *Duplex {
+	if len(control.DetachKeys) == 0 {
+		control.DetachKeys = DefaultDetachKeys
+	}
+	return &Duplex{
+		MultiplexedStream: newMultiplexedStream(ctx, reader, conn, conn, multiplex, true, logger, Options{}),
+		conn:              conn,
+		control:           control,
+	}
+}
+
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// CloseWrite closes stdin, signalling EOF to the process, while output can still be read
+func (d *Duplex) CloseWrite() error {
+	if conn, ok := d.conn.(closeWriter); ok {
+		return conn.CloseWrite()
+	}
+	return errors.New("connection does not support half-close")
+}
+
+// Resize resizes the TTY of the container or exec, it fails for processes without TTY
+func (d *Duplex) Resize(ctx context.Context, height, width uint) error {
+	if d.control.Resize == nil {
+		return errors.New("resize is not supported by this stream")
+	}
+	return d.control.Resize(ctx, height, width)
+}
+
+// Detach sends the detach sequence, the engine closes the stream while the process keeps running
+func (d *Duplex) Detach() error {
+	_, err := d.Write(d.control.DetachKeys)
+	return err
+}
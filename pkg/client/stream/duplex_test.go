@@ -0,0 +1,57 @@
+package stream
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func frame(streamType Type, data []byte) []byte {
+	header := make([]byte, 8)
+	header[0] = byte(streamType)
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+func TestDuplexHalfClose(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	// the server echoes stdin to stdout and a byte count to stderr once stdin is closed
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		input, _ := io.ReadAll(conn)
+		_, _ = conn.Write(frame(TypeStdout, input))
+		_, _ = conn.Write(frame(TypeStderr, []byte{byte(len(input))}))
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	duplex := NewDuplexStream(context.Background(), conn, conn, true, Control{}, zap.NewNop().Sugar())
+	defer func() { _ = duplex.Close() }()
+
+	_, err = duplex.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, duplex.CloseWrite())
+
+	var messages []Message
+	for message := range duplex.Messages() {
+		messages = append(messages, message)
+	}
+	require.Equal(t, []Message{
+		{StreamType: TypeStdout, Content: []byte("hello")},
+		{StreamType: TypeStderr, Content: []byte{5}},
+	}, messages)
+
+	require.Error(t, duplex.Resize(context.Background(), 24, 80))
+}
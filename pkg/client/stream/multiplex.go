@@ -2,11 +2,16 @@ package stream
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -42,17 +47,37 @@ func (s Type) IsValid() bool {
 type Message struct {
 	StreamType Type
 	Content    []byte
+	// Timestamp is only set if the stream parses timestamps, see WithTimestamps
+	Timestamp time.Time
 }
 
 type MultiplexedStream struct {
+	mutex  sync.Mutex
 	closer io.Closer
 	reader io.Reader
 	writer io.Writer
 
-	logger *zap.SugaredLogger
+	logger    *zap.SugaredLogger
+	options   Options
+	multiplex bool
+
+	// last is the latest timestamp delivered, seenAtLast the number of messages delivered with it
+	last       time.Time
+	seenAtLast int
+	// skipAtLast is the number of messages with timestamp last to drop after a reopen, as they were delivered before
+	skipAtLast int
+	resumed    bool
+
+	// partial holds the incomplete last line of each stream in line mode
+	partial map[Type]Message
+
+	err     error
+	dropped atomic.Uint64
 
 	messageChan chan Message
 	done        chan struct{}
+	stop        chan struct{}
+	stopOnce    sync.Once
 }
 
 func NewMultiplexedStream(
@@ -62,25 +87,51 @@ func NewMultiplexedStream(
 	writer io.Writer,
 	multiplex bool,
 	logger *zap.SugaredLogger,
+	opts ...Opt,
+) *MultiplexedStream {
+	return newMultiplexedStream(ctx, reader, closer, writer, multiplex, false, logger, RenderOptions(opts))
+}
+
+// newMultiplexedStream creates a stream, raw delivers simplex output in chunks as read instead of lines
+func newMultiplexedStream(
+	ctx context.Context,
+	reader io.Reader,
+	closer io.Closer,
+	writer io.Writer,
+	multiplex bool,
+	raw bool,
+	logger *zap.SugaredLogger,
+	options Options,
 ) *MultiplexedStream {
+	if options.BufferSize <= 0 {
+		options.BufferSize = DefaultBufferSize
+	}
 	m := &MultiplexedStream{
 		closer:      closer,
 		reader:      reader,
 		writer:      writer,
-		messageChan: make(chan Message, 100),
+		messageChan: make(chan Message, options.BufferSize),
 		done:        make(chan struct{}),
+		stop:        make(chan struct{}),
+		partial:     map[Type]Message{},
 		logger:      logger,
+		options:     options,
+		multiplex:   multiplex,
 	}
 
-	if multiplex {
-		go m.handleMultiplexOutput(ctx)
-	} else {
-		go m.handleSimplexOutput(ctx)
+	handler := m.handleSimplexOutput
+	switch {
+	case multiplex:
+		handler = m.handleMultiplexOutput
+	case raw, options.Lines:
+		handler = m.handleRawOutput
 	}
+	go m.run(ctx, handler)
 
 	return m
 }
 
+// Messages delivers the output, it stays empty if the output is written to writers (see WithOutput)
 func (m *MultiplexedStream) Messages() chan Message {
 	return m.messageChan
 }
@@ -89,6 +140,16 @@ func (m *MultiplexedStream) Done() <-chan struct{} {
 	return m.done
 }
 
+// Err returns the error the stream ended with, e.g. a failed write to an output writer. It is only valid after Done.
+func (m *MultiplexedStream) Err() error {
+	return m.err
+}
+
+// Dropped returns the number of messages dropped because the buffer was full, see WithDropOnOverflow
+func (m *MultiplexedStream) Dropped() uint64 {
+	return m.dropped.Load()
+}
+
 func (m *MultiplexedStream) Write(data []byte) (int, error) {
 	if m.writer == nil {
 		return 0, errors.New("writer is nil")
@@ -96,11 +157,20 @@ func (m *MultiplexedStream) Write(data []byte) (int, error) {
 	return m.writer.Write(data)
 }
 
+// Close closes the underlying reader and releases a delivery blocked on a full buffer
 func (m *MultiplexedStream) Close() error {
-	if m.closer == nil {
+	m.stopOnce.Do(func() { close(m.stop) })
+	return m.closeReader()
+}
+
+func (m *MultiplexedStream) closeReader() error {
+	m.mutex.Lock()
+	closer := m.closer
+	m.mutex.Unlock()
+	if closer == nil {
 		return nil
 	}
-	err := m.closer.Close()
+	err := closer.Close()
 	if err != nil {
 		m.logger.Errorf("failed to close multiplexed stream: %v", err)
 		return err
@@ -109,50 +179,261 @@ func (m *MultiplexedStream) Close() error {
 	return nil
 }
 
-func (m *MultiplexedStream) handleSimplexOutput(ctx context.Context) {
+func (m *MultiplexedStream) stopped(ctx context.Context) bool {
+	select {
+	case <-m.stop:
+		return true
+	default:
+		return ctx.Err() != nil || m.err != nil
+	}
+}
+
+// run reads the stream with handler until it ends, reopening it if it has a reopen function
+func (m *MultiplexedStream) run(ctx context.Context, handler func(ctx context.Context, reader io.Reader) error) {
 	defer close(m.done)
 	defer close(m.messageChan)
 	go func() {
-		<-ctx.Done()
-		_ = m.Close()
+		select {
+		case <-ctx.Done():
+			_ = m.Close()
+		case <-m.done:
+		}
 	}()
 
-	scanner := bufio.NewScanner(m.reader)
+	for {
+		m.mutex.Lock()
+		reader := m.reader
+		m.mutex.Unlock()
+		err := handler(ctx, reader)
+		if m.stopped(ctx) {
+			return
+		}
+		if m.options.Reopen == nil {
+			if err != nil {
+				m.logger.Errorf("%v", err)
+				m.err = err
+			}
+			m.flush(ctx)
+			return
+		}
+		if err != nil {
+			m.logger.Debugf("stream dropped, reopening: %v", err)
+		}
+		_ = m.closeReader()
+		if !m.reopen(ctx) {
+			m.flush(ctx)
+			return
+		}
+	}
+}
+
+func (m *MultiplexedStream) reopen(ctx context.Context) bool {
+	reader, err := m.options.Reopen(ctx, m.last)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.Errorf("failed to reopen stream: %v", err)
+			m.err = err
+		}
+		return false
+	}
+	if reader == nil {
+		return false
+	}
+	m.mutex.Lock()
+	m.reader, m.closer = reader, reader
+	m.mutex.Unlock()
+	if m.stopped(ctx) {
+		_ = reader.Close()
+		return false
+	}
+	m.resumed = true
+	m.skipAtLast = m.seenAtLast
+	return true
+}
+
+// emit passes a message as read through timestamp parsing and line assembly to deliver.
+// Multiplexed frames carry one timestamp each, simplex output has one per line.
+func (m *MultiplexedStream) emit(ctx context.Context, msg Message) error {
+	if m.multiplex && m.options.Timestamps {
+		msg = parseTimestamp(msg)
+	}
+	if !m.options.Lines {
+		return m.emitLine(ctx, msg)
+	}
+
+	pending, ok := m.partial[msg.StreamType]
+	if !ok {
+		pending = Message{StreamType: msg.StreamType, Timestamp: msg.Timestamp}
+	}
+	pending.Content = append(pending.Content, msg.Content...)
+	for {
+		end := bytes.IndexByte(pending.Content, '\n') + 1
+		if end == 0 && (m.options.MaxLineLength <= 0 || len(pending.Content) < m.options.MaxLineLength) {
+			break
+		}
+		if m.options.MaxLineLength > 0 && (end == 0 || end > m.options.MaxLineLength) {
+			end = m.options.MaxLineLength
+		}
+		line := pending
+		line.Content = bytes.Clone(pending.Content[:end])
+		if err := m.emitLine(ctx, line); err != nil {
+			return err
+		}
+		pending.Content = pending.Content[end:]
+		pending.Timestamp = msg.Timestamp
+	}
+	if len(pending.Content) == 0 {
+		delete(m.partial, msg.StreamType)
+	} else {
+		m.partial[msg.StreamType] = pending
+	}
+	return nil
+}
+
+// flush delivers the incomplete last lines at the end of the stream
+func (m *MultiplexedStream) flush(ctx context.Context) {
+	for _, streamType := range []Type{TypeStdin, TypeStdout, TypeStderr, TypeSystemError} {
+		if pending, ok := m.partial[streamType]; ok {
+			delete(m.partial, streamType)
+			if err := m.emitLine(ctx, pending); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// emitLine parses the timestamp of simplex lines and delivers msg, unless it was already delivered before a reopen
+func (m *MultiplexedStream) emitLine(ctx context.Context, msg Message) error {
+	if !m.multiplex && m.options.Timestamps {
+		msg = parseTimestamp(msg)
+	}
+	if !msg.Timestamp.IsZero() {
+		if m.resumed {
+			if msg.Timestamp.Before(m.last) {
+				return nil
+			}
+			if msg.Timestamp.Equal(m.last) && m.skipAtLast > 0 {
+				m.skipAtLast--
+				return nil
+			}
+		}
+		if msg.Timestamp.After(m.last) {
+			m.last, m.seenAtLast, m.skipAtLast = msg.Timestamp, 0, 0
+		}
+		if msg.Timestamp.Equal(m.last) {
+			m.seenAtLast++
+		}
+	}
+	return m.deliver(ctx, msg)
+}
+
+// deliver writes msg to the output writers or sends it, blocking while the buffer is full unless messages are
+// dropped on overflow
+func (m *MultiplexedStream) deliver(ctx context.Context, msg Message) error {
+	if m.options.Stdout != nil || m.options.Stderr != nil {
+		writer := m.options.Stdout
+		if msg.StreamType != TypeStdout {
+			writer = m.options.Stderr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := writer.Write(msg.Content); err != nil {
+			m.err = fmt.Errorf("failed to write %s: %w", msg.StreamType.Name(), err)
+			return m.err
+		}
+		return nil
+	}
+
+	select {
+	case m.messageChan <- msg:
+		return nil
+	default:
+	}
+	if m.options.DropOnOverflow {
+		m.dropped.Add(1)
+		return nil
+	}
+	select {
+	case m.messageChan <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.stop:
+		return net.ErrClosed
+	}
+}
+
+// parseTimestamp strips the RFC3339Nano prefix the engines add to log lines
+func parseTimestamp(msg Message) Message {
+	prefix, rest, ok := bytes.Cut(msg.Content, []byte{' '})
+	if !ok {
+		prefix, rest = msg.Content, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, string(prefix))
+	if err != nil {
+		return msg
+	}
+	msg.Timestamp = timestamp
+	msg.Content = rest
+	return msg
+}
+
+func (m *MultiplexedStream) handleSimplexOutput(ctx context.Context, reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
 	for ctx.Err() == nil && scanner.Scan() {
-		line := scanner.Bytes()
-		m.messageChan <- Message{
+		// the scanner reuses its buffer
+		line := bytes.Clone(scanner.Bytes())
+		err := m.emit(ctx, Message{
 			Content:    line,
 			StreamType: TypeStdout,
+		})
+		if err != nil {
+			return err
 		}
 	}
-	err := scanner.Err()
-	if err != nil {
-		m.logger.Errorf("failed to read simplex output: %v", err)
+	if err := scanner.Err(); err != nil && !isEOF(err) {
+		return fmt.Errorf("failed to read simplex output: %w", err)
 	}
+	return nil
 }
 
-func (m *MultiplexedStream) handleMultiplexOutput(ctx context.Context) {
-	defer close(m.done)
-	defer close(m.messageChan)
-	go func() {
-		<-ctx.Done()
-		_ = m.Close()
-	}()
+func (m *MultiplexedStream) handleRawOutput(ctx context.Context, reader io.Reader) error {
+	buf := make([]byte, 32*1024)
+	for ctx.Err() == nil {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			emitErr := m.emit(ctx, Message{
+				Content:    bytes.Clone(buf[:n]),
+				StreamType: TypeStdout,
+			})
+			if emitErr != nil {
+				return emitErr
+			}
+		}
+		if isEOF(err) {
+			m.logger.Debugf("EOF")
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read raw output: %w", err)
+		}
+	}
+	return nil
+}
 
+func (m *MultiplexedStream) handleMultiplexOutput(ctx context.Context, reader io.Reader) error {
 	header := make([]byte, 8)
 	for ctx.Err() == nil {
-		_, err := io.ReadFull(m.reader, header)
+		_, err := io.ReadFull(reader, header)
 		if isEOF(err) {
 			m.logger.Debugf("EOF")
 			break
 		} else if err != nil {
-			m.logger.Errorf("failed to read multiplexed output header: %v", err)
-			return
+			return fmt.Errorf("failed to read multiplexed output header: %w", err)
 		}
 		streamType := Type(header[0])
 		if !streamType.IsValid() {
-			m.logger.Errorf("invalid stream type: %d", streamType)
-			return
+			return fmt.Errorf("invalid stream type: %d", streamType)
 		}
 		size := uint32(0)
 		for i := 0; i < 4; i++ {
@@ -161,21 +442,24 @@ func (m *MultiplexedStream) handleMultiplexOutput(ctx context.Context) {
 		}
 
 		data := make([]byte, size)
-		_, err = io.ReadFull(m.reader, data)
+		_, err = io.ReadFull(reader, data)
 		if isEOF(err) {
 			m.logger.Debugf("EOF")
 			break
 		} else if err != nil {
-			m.logger.Errorf("failed to read multiplexed output data: %v", err)
-			return
+			return fmt.Errorf("failed to read multiplexed output data: %w", err)
 		}
 
-		m.messageChan <- Message{
+		err = m.emit(ctx, Message{
 			StreamType: streamType,
 			Content:    data,
+		})
+		if err != nil {
+			return err
 		}
 	}
-	m.logger.Debugf("multiplexed output handling completed, closing channels")
+	m.logger.Debugf("multiplexed output handling completed")
+	return nil
 }
 
 func isEOF(err error) bool {
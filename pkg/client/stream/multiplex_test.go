@@ -0,0 +1,152 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func logLine(streamType Type, timestamp time.Time, line string) []byte {
+	return frame(streamType, []byte(timestamp.Format(time.RFC3339Nano)+" "+line+"\n"))
+}
+
+func collect(m *MultiplexedStream) []Message {
+	var messages []Message
+	for message := range m.Messages() {
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func TestTimestamps(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	body := bytes.Join([][]byte{
+		logLine(TypeStdout, ts, "hello"),
+		frame(TypeStderr, []byte("no timestamp\n")),
+	}, nil)
+	m := NewMultiplexedStream(
+		context.Background(), bytes.NewReader(body), nil, nil, true, zap.NewNop().Sugar(), WithTimestamps(),
+	)
+	require.Equal(t, []Message{
+		{StreamType: TypeStdout, Content: []byte("hello\n"), Timestamp: ts},
+		{StreamType: TypeStderr, Content: []byte("no timestamp\n")},
+	}, collect(m))
+}
+
+func TestReopenSkipsDelivered(t *testing.T) {
+	t1 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Second)
+	t3 := t2.Add(time.Second)
+	first := bytes.Join([][]byte{
+		logLine(TypeStdout, t1, "a"),
+		logLine(TypeStdout, t2, "b"),
+		// the connection drops in the middle of the next frame
+		logLine(TypeStdout, t2, "c")[:10],
+	}, nil)
+	// the engine's since filter is inclusive, so the resumed stream repeats the lines logged at t2
+	second := bytes.Join([][]byte{
+		logLine(TypeStdout, t2, "b"),
+		logLine(TypeStdout, t2, "c"),
+		logLine(TypeStdout, t3, "d"),
+	}, nil)
+
+	var sinces []time.Time
+	reopen := func(_ context.Context, since time.Time) (io.ReadCloser, error) {
+		sinces = append(sinces, since)
+		if len(sinces) > 1 {
+			return nil, nil
+		}
+		return io.NopCloser(bytes.NewReader(second)), nil
+	}
+	m := NewMultiplexedStream(
+		context.Background(), bytes.NewReader(first), nil, nil, true, zap.NewNop().Sugar(), WithReopen(reopen),
+	)
+
+	var lines []string
+	for _, message := range collect(m) {
+		lines = append(lines, string(message.Content))
+	}
+	require.Equal(t, []string{"a\n", "b\n", "c\n", "d\n"}, lines)
+	require.Equal(t, []time.Time{t2, t3}, sinces)
+}
+
+func TestLines(t *testing.T) {
+	body := bytes.Join([][]byte{
+		frame(TypeStdout, []byte("par")),
+		frame(TypeStderr, []byte("err\n")),
+		frame(TypeStdout, []byte("tial\nlong line\nrest")),
+	}, nil)
+	m := NewMultiplexedStream(
+		context.Background(), bytes.NewReader(body), nil, nil, true, zap.NewNop().Sugar(), WithLines(5),
+	)
+	require.Equal(t, []Message{
+		{StreamType: TypeStderr, Content: []byte("err\n")},
+		{StreamType: TypeStdout, Content: []byte("parti")},
+		{StreamType: TypeStdout, Content: []byte("al\n")},
+		{StreamType: TypeStdout, Content: []byte("long ")},
+		{StreamType: TypeStdout, Content: []byte("line\n")},
+		{StreamType: TypeStdout, Content: []byte("rest")},
+	}, collect(m))
+}
+
+func TestSimplexLines(t *testing.T) {
+	m := NewMultiplexedStream(
+		context.Background(), bytes.NewReader([]byte("a\r\nb\nc")), nil, nil, false, zap.NewNop().Sugar(),
+		WithLines(0),
+	)
+	require.Equal(t, []Message{
+		{StreamType: TypeStdout, Content: []byte("a\r\n")},
+		{StreamType: TypeStdout, Content: []byte("b\n")},
+		{StreamType: TypeStdout, Content: []byte("c")},
+	}, collect(m))
+}
+
+func TestDemux(t *testing.T) {
+	body := bytes.Join([][]byte{
+		frame(TypeStdout, []byte("out\n")),
+		frame(TypeStderr, []byte("err\n")),
+		frame(TypeSystemError, []byte("system\n")),
+	}, nil)
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, Demux(context.Background(), bytes.NewReader(body), &stdout, &stderr))
+	require.Equal(t, "out\n", stdout.String())
+	require.Equal(t, "err\nsystem\n", stderr.String())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestDemuxWriteError(t *testing.T) {
+	err := Demux(context.Background(), bytes.NewReader(frame(TypeStdout, []byte("out"))), failingWriter{}, nil)
+	require.ErrorIs(t, err, io.ErrShortWrite)
+}
+
+func TestBackpressure(t *testing.T) {
+	body := bytes.Repeat(frame(TypeStdout, []byte("x")), 10)
+
+	dropping := NewMultiplexedStream(
+		context.Background(), bytes.NewReader(body), nil, nil, true, zap.NewNop().Sugar(),
+		WithBufferSize(2), WithDropOnOverflow(),
+	)
+	<-dropping.Done()
+	require.Len(t, collect(dropping), 2)
+	require.Equal(t, uint64(8), dropping.Dropped())
+
+	// a blocked stream is released by cancelling its context, although nobody reads its messages
+	ctx, cancel := context.WithCancel(context.Background())
+	blocking := NewMultiplexedStream(ctx, bytes.NewReader(body), nil, nil, true, zap.NewNop().Sugar(), WithBufferSize(2))
+	cancel()
+	select {
+	case <-blocking.Done():
+	case <-time.After(time.Second):
+		t.Fatal("stream did not end after cancellation")
+	}
+}
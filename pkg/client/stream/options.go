@@ -0,0 +1,99 @@
+package stream
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// DefaultBufferSize is the number of messages buffered before the stream applies backpressure
+const DefaultBufferSize = 100
+
+// Options configure how a MultiplexedStream delivers messages
+type Options struct {
+	// Timestamps parses the RFC3339Nano prefix of each message into Message.Timestamp and strips it from the content
+	Timestamps bool
+	// Reopen is called when the stream ends or drops before its context is done
+	Reopen Reopen
+	// Lines delivers complete lines (including the newline) of each stream in every mode, instead of frames or
+	// chunks as read. The last line is delivered without newline if the output does not end with one.
+	Lines bool
+	// MaxLineLength splits longer lines in line mode, 0 for unlimited
+	MaxLineLength int
+	// Stdout and Stderr receive the output instead of Messages, system errors are written to Stderr
+	Stdout io.Writer
+	Stderr io.Writer
+	// BufferSize is the capacity of Messages, DefaultBufferSize if not set
+	BufferSize int
+	// DropOnOverflow drops messages while the buffer is full instead of pausing the reader
+	DropOnOverflow bool
+}
+
+// Reopen reopens a stream from since (inclusive, zero if no timestamp was seen yet).
+// A nil reader ends the stream. Messages delivered before the reopen are dropped by their timestamp.
+type Reopen func(ctx context.Context, since time.Time) (io.ReadCloser, error)
+
+type Opt func(*Options)
+
+// WithTimestamps parses the timestamp prefix of log lines
+func WithTimestamps() Opt {
+	return func(o *Options) {
+		o.Timestamps = true
+	}
+}
+
+// WithReopen resumes the stream with reopen after it ended or dropped, it implies WithTimestamps
+func WithReopen(reopen Reopen) Opt {
+	return func(o *Options) {
+		o.Timestamps = true
+		o.Reopen = reopen
+	}
+}
+
+// WithLines delivers complete lines, lines longer than maxLength are split (0 for unlimited)
+func WithLines(maxLength int) Opt {
+	return func(o *Options) {
+		o.Lines = true
+		o.MaxLineLength = maxLength
+	}
+}
+
+// WithOutput writes stdout and stderr to the writers instead of delivering messages, a nil writer discards the
+// stream. A failed write ends the stream with the error, see MultiplexedStream.Err.
+func WithOutput(stdout, stderr io.Writer) Opt {
+	return func(o *Options) {
+		o.Stdout = orDiscard(stdout)
+		o.Stderr = orDiscard(stderr)
+	}
+}
+
+func orDiscard(writer io.Writer) io.Writer {
+	if writer == nil {
+		return io.Discard
+	}
+	return writer
+}
+
+// WithBufferSize sets the number of buffered messages
+func WithBufferSize(size int) Opt {
+	return func(o *Options) {
+		o.BufferSize = size
+	}
+}
+
+// WithDropOnOverflow drops messages while the buffer is full, see MultiplexedStream.Dropped.
+// By default the stream stops reading until messages are consumed, the stream is cancelled or closed.
+func WithDropOnOverflow() Opt {
+	return func(o *Options) {
+		o.DropOnOverflow = true
+	}
+}
+
+// RenderOptions applies opts on top of the zero options
+func RenderOptions(opts []Opt) Options {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
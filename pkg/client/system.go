@@ -2,42 +2,69 @@ package client
 
 import (
 	"context"
+	"fmt"
 
-	"github.com/containerd/platforms"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
-	v2 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	podmanclient "github.com/silenium-dev/docker-wrapper/pkg/client/podman/client"
 )
 
+// SystemCapabilities returns the capabilities of the engine, they are loaded once and refreshed after the TTL
+// configured with WithCapabilitiesTTL
+func (c *Client) SystemCapabilities(ctx context.Context) (*capabilities.Capabilities, error) {
+	return c.capabilities.Get(ctx)
+}
+
+// SystemCapabilitiesRefresh loads the capabilities of the engine again, e.g. after it was reconfigured
+func (c *Client) SystemCapabilitiesRefresh(ctx context.Context) (*capabilities.Capabilities, error) {
+	return c.capabilities.Refresh(ctx)
+}
+
+func (c *Client) loadCapabilities(ctx context.Context) (*capabilities.Capabilities, error) {
+	version, err := c.ServerVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get engine version: %w", err)
+	}
+	info, err := c.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get engine info: %w", err)
+	}
+	ping, err := c.Ping(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ping engine: %w", err)
+	}
+	result := capabilities.FromEngine(version, info, ping, nil)
+	if !result.IsPodman() {
+		c.discoverPlatforms(ctx, result)
+		return result, nil
+	}
+
+	podman, err := podmanclient.FromPodmanHost(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	podmanInfo, err := podman.SystemInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get podman info: %w", err)
+	}
+	result = capabilities.FromEngine(version, info, ping, &podmanInfo)
+	c.discoverPlatforms(ctx, result)
+	return result, nil
+}
+
 func (c *Client) SystemIsPodman(ctx context.Context) (bool, error) {
-	ver, err := c.ServerVersion(ctx)
+	caps, err := c.SystemCapabilities(ctx)
 	if err != nil {
 		return false, err
 	}
-	for _, c := range ver.Components {
-		if c.Name == "Podman Engine" {
-			return true, nil
-		}
-	}
-	return false, nil
+	return caps.IsPodman(), nil
 }
 
 func (c *Client) SystemDefaultPlatform(ctx context.Context) (*v1.Platform, error) {
-	info, err := c.Info(ctx)
+	caps, err := c.SystemCapabilities(ctx)
 	if err != nil {
 		return nil, err
 	}
-	normalized := platforms.Normalize(
-		v2.Platform{
-			OS:           info.OSType,
-			Architecture: info.Architecture,
-		},
-	)
-
-	return &v1.Platform{
-		OS:           normalized.OS,
-		Architecture: normalized.Architecture,
-		OSVersion:    normalized.OSVersion,
-		Variant:      normalized.Variant,
-		OSFeatures:   normalized.OSFeatures,
-	}, nil
+	platform := caps.DefaultPlatform
+	return &platform, nil
 }
@@ -10,33 +10,108 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/hostip"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/pull"
 	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
 	"k8s.io/apimachinery/pkg/util/rand"
 )
 
 func (c *Client) SystemHostIPFromContainers(ctx context.Context, netId *string) (net.IP, error) {
-	c.hostFromContainerMutex.RLock()
-	if c.hostFromContainerAddr != nil { // Fast path
-		defer c.hostFromContainerMutex.RUnlock()
-		return c.hostFromContainerAddr, nil
+	result, err := c.SystemHostIP(ctx, netId)
+	if err != nil {
+		return nil, err
+	}
+	return result.IP, nil
+}
+
+// SystemHostIP discovers the IP containers on netId (the default network if nil) reach the host with.
+// Methods without a helper container are tried first (see hostip.Tiers), the result reports the method which
+// succeeded.
+// Results are cached per network until the network is removed, see SystemHostIPRefresh.
+func (c *Client) SystemHostIP(ctx context.Context, netId *string) (*hostip.Result, error) {
+	c.watchNetworkRemovals()
+	return c.hostIPs.Get(ctx, c.DaemonHost(), networkKey(netId), func(ctx context.Context) (*hostip.Result, error) {
+		return c.discoverHostIP(ctx, netId)
+	})
+}
+
+// SystemHostIPRefresh drops the cached host IP of netId and discovers it again
+func (c *Client) SystemHostIPRefresh(ctx context.Context, netId *string) (*hostip.Result, error) {
+	c.hostIPs.InvalidateKey(networkKey(netId))
+	return c.SystemHostIP(ctx, netId)
+}
+
+func networkKey(netId *string) string {
+	if netId == nil {
+		return ""
 	}
-	c.hostFromContainerMutex.RUnlock()
-	c.hostFromContainerMutex.Lock()
-	defer c.hostFromContainerMutex.Unlock()
-	if c.hostFromContainerAddr != nil { // Recheck
-		return c.hostFromContainerAddr, nil
+	return *netId
+}
+
+func (c *Client) discoverHostIP(ctx context.Context, netId *string) (*hostip.Result, error) {
+	engine, err := c.SystemCapabilities(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return hostip.Discover(ctx, hostip.Tiers(engine, hostip.Discoverers{
+		Gateway: func(ctx context.Context) (net.IP, error) {
+			return c.hostIPFromGateway(ctx, engine, netId)
+		},
+		PodmanInfo: func(context.Context) (net.IP, error) {
+			return hostip.RootlessHostIP(engine.RootlessNetwork, engine.EngineVersion)
+		},
+		Hostname: func(ctx context.Context) (net.IP, error) {
+			return c.hostIPFromProbe(ctx, engine, netId, pull.PolicyNever)
+		},
+		Probe: func(ctx context.Context) (net.IP, error) {
+			return c.hostIPFromProbe(ctx, engine, netId, pull.PolicyMissing)
+		},
+	}), c.logger)
+}
 
-	isPodman, err := c.SystemIsPodman(ctx)
+// hostIPFromGateway returns the IPAM gateway of the network, which only is the host on rootful local engines
+func (c *Client) hostIPFromGateway(ctx context.Context, engine *capabilities.Capabilities, netId *string) (net.IP, error) {
+	name := "bridge"
+	if engine.IsPodman() {
+		name = "podman"
+	}
+	if netId != nil {
+		name = *netId
+	}
+	inspect, err := c.NetworkInspect(ctx, name, network.InspectOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if Podman: %w", err)
+		return nil, fmt.Errorf("failed to inspect network %s: %w", name, err)
 	}
+	var fallback net.IP
+	for _, config := range inspect.IPAM.Config {
+		ip := net.ParseIP(config.Gateway)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			return ip, nil
+		}
+		if fallback == nil {
+			fallback = ip
+		}
+	}
+	return fallback, nil
+}
+
+// hostIPFromProbe starts a helper container on the network and reads the host IP from it. If the engine has a host
+// alias (see hostip.Hostname), the container resolves it, as the gateway of its network isn't the host. Otherwise the
+// gateway is read. policy decides whether the helper image may be pulled.
+func (c *Client) hostIPFromProbe(
+	ctx context.Context, engine *capabilities.Capabilities, netId *string, policy pull.Policy,
+) (net.IP, error) {
+	alias := hostip.Hostname(engine)
 
 	imgRef, err := reference.ParseDockerRef(c.imageProvider.GetDnsUtilImage())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse image reference %s: %w", c.imageProvider.GetDnsUtilImage(), err)
 	}
-	dig, err := c.ImagePullSimple(ctx, imgRef, image.PullOptions{})
+	dig, err := c.ImagePullSimple(ctx, imgRef, image.PullOptions{}, pull.WithPolicy(policy))
 	if err != nil {
 		return nil, fmt.Errorf("failed to pull image %s: %w", imgRef.String(), err)
 	}
@@ -47,8 +122,9 @@ func (c *Client) SystemHostIPFromContainers(ctx context.Context, netId *string)
 	}
 
 	command := []string{"sh", "-c", "sleep infinity"}
-	if isPodman {
-		command = []string{"dig", "+short", "A", "host.docker.internal"}
+	if alias != "" {
+		// getent reads the hosts file podman writes the alias to, as well as DNS
+		command = []string{"getent", "ahostsv4", alias}
 	}
 
 	cont, err := c.ContainerCreate(
@@ -77,7 +153,7 @@ func (c *Client) SystemHostIPFromContainers(ctx context.Context, netId *string)
 		return nil, fmt.Errorf("failed to inspect container %s: %w", cont.ID, err)
 	}
 	var ipAddrStr string
-	if isPodman {
+	if alias != "" {
 		multiplex, err := c.StreamLogs(ctx, cont.ID, true)
 		if err != nil {
 			return nil, err
@@ -89,7 +165,12 @@ func (c *Client) SystemHostIPFromContainers(ctx context.Context, netId *string)
 		if ipAddrByteMsg.StreamType != stream.TypeStdout {
 			return nil, fmt.Errorf("unexpected stream type %s from container %s", ipAddrByteMsg.StreamType.Name(), cont.ID)
 		}
-		ipAddrStr = strings.TrimSpace(string(ipAddrByteMsg.Content))
+		// the lines look like "192.168.65.254  STREAM host.docker.internal"
+		fields := strings.Fields(string(ipAddrByteMsg.Content))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("container %s could not resolve %s", cont.ID, alias)
+		}
+		ipAddrStr = fields[0]
 	} else if netId == nil {
 		ipAddrStr = inspect.NetworkSettings.Gateway
 	} else {
@@ -114,6 +195,5 @@ func (c *Client) SystemHostIPFromContainers(ctx context.Context, netId *string)
 		return nil, fmt.Errorf("failed to parse IP address from: %s", ipAddrStr)
 	}
 
-	c.hostFromContainerAddr = ipAddr
-	return c.hostFromContainerAddr, nil
+	return ipAddr, nil
 }
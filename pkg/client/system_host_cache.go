@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// networkEventsRetry is the delay before the network event stream is reopened after it failed
+const networkEventsRetry = 5 * time.Second
+
+// watchNetworkRemovals starts invalidating cached host IPs of removed networks, once per client.
+// The first subscription is made before it returns, so removals during the first discovery aren't missed.
+func (c *Client) watchNetworkRemovals() {
+	c.hostIPWatch.Do(func() {
+		messages, errs := c.subscribeNetworkEvents(c.closeCtx)
+		go func() {
+			for {
+				c.watchNetworkEvents(c.closeCtx, messages, errs)
+				// removals may have been missed while the stream was down
+				c.hostIPs.Clear()
+				select {
+				case <-c.closeCtx.Done():
+					return
+				case <-time.After(networkEventsRetry):
+				}
+				messages, errs = c.subscribeNetworkEvents(c.closeCtx)
+			}
+		}()
+	})
+}
+
+// subscribeNetworkEvents opens the network event stream, the engine delivers events once it returns
+func (c *Client) subscribeNetworkEvents(ctx context.Context) (<-chan events.Message, <-chan error) {
+	return c.Events(ctx, events.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("type", string(events.NetworkEventType))),
+	})
+}
+
+// watchNetworkEvents invalidates cached host IPs until the event stream fails
+func (c *Client) watchNetworkEvents(ctx context.Context, messages <-chan events.Message, errs <-chan error) {
+	for {
+		select {
+		case msg := <-messages:
+			switch msg.Action {
+			// podman reports removals as remove, docker as destroy
+			case events.ActionDestroy, events.ActionRemove:
+				c.logger.Debugf("network %s removed, dropping its cached host IP", msg.Actor.ID)
+				c.hostIPs.Invalidate(msg.Actor.ID, msg.Actor.Attributes["name"])
+			case events.ActionPrune:
+				c.hostIPs.Clear()
+			}
+		case err := <-errs:
+			if ctx.Err() == nil {
+				c.logger.Debugf("network event stream failed: %v", err)
+			}
+			return
+		}
+	}
+}
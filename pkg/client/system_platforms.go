@@ -0,0 +1,149 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	controlapi "github.com/moby/buildkit/api/services/control"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/capabilities"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/platform"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/provider"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+// SystemPlatforms returns the platforms the engine runs containers of, natively or emulated, the default platform
+// first. Emulated platforms are found in the BuildKit workers and the binfmt registrations of a local engine.
+// If neither reports any and the client was created WithPlatformProbe, a privileged helper container reads the
+// registrations of the engine's kernel. Its result is cached until the capabilities are refreshed.
+func (c *Client) SystemPlatforms(ctx context.Context) ([]v1.Platform, error) {
+	caps, err := c.SystemCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !c.platformProbe || len(caps.Platforms) > 1 {
+		return caps.Platforms, nil
+	}
+
+	c.platformsMutex.Lock()
+	defer c.platformsMutex.Unlock()
+	if c.probedPlatforms == nil || c.probedPlatforms.caps != caps {
+		probed, err := c.platformsFromProbe(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.probedPlatforms = &probedPlatforms{caps: caps, platforms: platform.Merge(caps.Platforms, probed...)}
+	}
+	return c.probedPlatforms.platforms, nil
+}
+
+// probedPlatforms are the platforms found by the helper container for a capabilities load
+type probedPlatforms struct {
+	caps      *capabilities.Capabilities
+	platforms []v1.Platform
+}
+
+// discoverPlatforms adds the platforms which can be found without a helper container to caps, failures are only logged
+func (c *Client) discoverPlatforms(ctx context.Context, caps *capabilities.Capabilities) {
+	if caps.BuildKit {
+		found, err := c.platformsFromBuildKit(ctx)
+		if err != nil {
+			c.logger.Debugf("failed to list BuildKit worker platforms: %v", err)
+		}
+		caps.AddPlatforms(found...)
+	}
+	// the binfmt registrations are per kernel, they are only the engine's if it runs on this host
+	if runtime.GOOS == "linux" && strings.HasPrefix(c.DaemonHost(), "unix://") && !caps.Desktop() {
+		found, err := platform.FromBinfmt(platform.BinfmtDir)
+		if err != nil {
+			c.logger.Debugf("failed to read binfmt registrations: %v", err)
+		}
+		caps.AddPlatforms(found...)
+	}
+}
+
+// platformsFromBuildKit lists the platforms of the engine's BuildKit workers, they include the emulated platforms
+func (c *Client) platformsFromBuildKit(ctx context.Context) ([]v1.Platform, error) {
+	conn, err := grpc.NewClient(
+		"passthrough:///buildkit",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return c.DialHijack(ctx, "/grpc", "h2c", nil)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	response, err := controlapi.NewControlClient(conn).ListWorkers(ctx, &controlapi.ListWorkersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	var result []v1.Platform
+	for _, worker := range response.Record {
+		for _, p := range worker.Platforms {
+			result = platform.Merge(result, v1.Platform{
+				OS: p.OS, Architecture: p.Architecture, Variant: p.Variant, OSVersion: p.OSVersion,
+			})
+		}
+	}
+	return result, nil
+}
+
+// platformsFromProbe runs the binfmt helper image, which prints the platforms the engine's kernel runs
+func (c *Client) platformsFromProbe(ctx context.Context) ([]v1.Platform, error) {
+	binfmtImage := provider.BinfmtImage(c.imageProvider)
+	imgRef, err := reference.ParseDockerRef(binfmtImage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %s: %w", binfmtImage, err)
+	}
+	dig, err := c.ImagePullSimple(ctx, imgRef, image.PullOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull image %s: %w", imgRef.String(), err)
+	}
+
+	// binfmt_misc is only mounted in privileged containers
+	cont, err := c.ContainerCreate(
+		ctx, &container.Config{Image: dig.String()}, &container.HostConfig{Privileged: true}, nil, nil,
+		rand.String(16),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer c.ContainerRemove(context.Background(), cont.ID, container.RemoveOptions{Force: true})
+
+	if err := c.ContainerStart(ctx, cont.ID, container.StartOptions{}); err != nil {
+		return nil, err
+	}
+	waitChan, errChan := c.ContainerWait(ctx, cont.ID, container.WaitConditionNotRunning)
+	select {
+	case result := <-waitChan:
+		if result.StatusCode != 0 {
+			return nil, fmt.Errorf("binfmt container %s exited with %d", cont.ID, result.StatusCode)
+		}
+	case err := <-errChan:
+		return nil, err
+	}
+
+	logs, err := c.ContainerLogs(ctx, cont.ID, container.LogsOptions{ShowStdout: true})
+	if err != nil {
+		return nil, err
+	}
+	var stdout bytes.Buffer
+	if err := stream.Demux(ctx, logs, &stdout, io.Discard); err != nil {
+		return nil, err
+	}
+	return platform.ParseBinfmtStatus(&stdout)
+}
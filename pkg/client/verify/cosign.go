@@ -0,0 +1,180 @@
+package verify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/inspect"
+	"github.com/silenium-dev/docker-wrapper/pkg/errors"
+)
+
+const (
+	// SimpleSigningMediaType is the layer media type of cosign signature payloads
+	SimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// SignatureAnnotation holds the base64 encoded signature of the payload
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// SignatureArtifactType is the artifact type of cosign signatures stored as OCI 1.1 referrers
+	SignatureArtifactType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+)
+
+// maxPayloadSize limits the signature payloads read from the registry
+const maxPayloadSize = 1 << 20
+
+// simpleSigning is the payload format of cosign signatures
+type simpleSigning struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// CosignVerifier accepts images with at least one cosign (simple signing) signature made by one of its keys
+type CosignVerifier struct {
+	keys []crypto.PublicKey
+}
+
+func NewCosignVerifier(keys ...crypto.PublicKey) *CosignVerifier {
+	return &CosignVerifier{keys: keys}
+}
+
+// NewCosignVerifierFromFiles loads PEM encoded public keys (as written by cosign generate-key-pair)
+func NewCosignVerifierFromFiles(paths ...string) (*CosignVerifier, error) {
+	keys := make([]crypto.PublicKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key %s: %w", path, err)
+		}
+		key, err := ParsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewCosignVerifier(keys...), nil
+}
+
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// Verify looks up the signatures of dig in the repository of ref and checks them against the configured keys
+func (v *CosignVerifier) Verify(ref reference.Named, dig v1.Hash, remoteOpts []remote.Option) error {
+	fail := func(reason string, args ...any) error {
+		return &errors.VerificationError{Ref: ref.String(), Digest: dig.String(), Reason: fmt.Sprintf(reason, args...)}
+	}
+	if len(v.keys) == 0 {
+		return fail("no public keys configured")
+	}
+	repo, err := name.NewRepository(ref.Name())
+	if err != nil {
+		return err
+	}
+	referrers, err := inspect.ReferrersOf(repo, dig, remoteOpts)
+	if err != nil {
+		return fail("failed to look up signatures: %v", err)
+	}
+
+	signatures := 0
+	var lastErr error
+	for _, referrer := range referrers {
+		if !isSignature(referrer) {
+			continue
+		}
+		for _, layer := range referrer.Layers() {
+			if layer.MediaType != SimpleSigningMediaType {
+				continue
+			}
+			signatures++
+			if lastErr = v.verifyLayer(referrer, layer, dig, remoteOpts); lastErr == nil {
+				return nil
+			}
+		}
+	}
+	if signatures == 0 {
+		return fail("no signatures found")
+	}
+	return fail("none of %d signatures is valid, last error: %v", signatures, lastErr)
+}
+
+func isSignature(referrer inspect.Referrer) bool {
+	if referrer.Source == inspect.SourceCosignTag {
+		return strings.HasSuffix(referrer.Tag, ".sig")
+	}
+	return referrer.ArtifactType == SignatureArtifactType
+}
+
+func (v *CosignVerifier) verifyLayer(
+	referrer inspect.Referrer, layer v1.Descriptor, dig v1.Hash, remoteOpts []remote.Option,
+) error {
+	signature, err := base64.StdEncoding.DecodeString(layer.Annotations[SignatureAnnotation])
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("missing or invalid signature annotation")
+	}
+	blob, err := inspect.FetchBlob(referrer, layer, remoteOpts)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = blob.Close() }()
+	payload, err := io.ReadAll(io.LimitReader(blob, maxPayloadSize))
+	if err != nil {
+		return fmt.Errorf("failed to read signature payload: %w", err)
+	}
+	if actual := sha256.Sum256(payload); layer.Digest.Algorithm == "sha256" &&
+		fmt.Sprintf("%x", actual) != layer.Digest.Hex {
+		return fmt.Errorf("signature payload does not match its digest")
+	}
+
+	var parsed simpleSigning
+	if err := json.Unmarshal(payload, &parsed); err != nil {
+		return fmt.Errorf("invalid signature payload: %w", err)
+	}
+	if parsed.Critical.Image.DockerManifestDigest != dig.String() {
+		return fmt.Errorf("signature is for %s", parsed.Critical.Image.DockerManifestDigest)
+	}
+
+	for _, key := range v.keys {
+		if verifySignature(key, payload, signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature does not match any key")
+}
+
+func verifySignature(key crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, hash[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil ||
+			rsa.VerifyPSS(key, crypto.SHA256, hash[:], signature, nil) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, signature)
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package verify
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/silenium-dev/docker-wrapper/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, tag name.Tag, dig v1.Hash) {
+	payload := []byte(fmt.Sprintf(
+		`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`,
+		tag.Context().Name(), dig.String(),
+	))
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	sig, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, SimpleSigningMediaType),
+		Annotations: map[string]string{SignatureAnnotation: base64.StdEncoding.EncodeToString(signature)},
+	})
+	require.NoError(t, err)
+	sigTag := tag.Context().Tag(strings.Replace(dig.String(), ":", "-", 1) + ".sig")
+	require.NoError(t, remote.Write(sigTag, sig))
+}
+
+func TestCosignVerifier(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	signedTag, err := name.NewTag(host + "/test/signed:latest")
+	require.NoError(t, err)
+	signed, err := random.Image(256, 1)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(signedTag, signed))
+	signedDigest, err := signed.Digest()
+	require.NoError(t, err)
+	sign(t, signer, signedTag, signedDigest)
+
+	unsignedTag, err := name.NewTag(host + "/test/unsigned:latest")
+	require.NoError(t, err)
+	unsigned, err := random.Image(256, 1)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(unsignedTag, unsigned))
+	unsignedDigest, err := unsigned.Digest()
+	require.NoError(t, err)
+
+	signedRef, err := reference.ParseNamed(signedTag.String())
+	require.NoError(t, err)
+	unsignedRef, err := reference.ParseNamed(unsignedTag.String())
+	require.NoError(t, err)
+
+	require.NoError(t, NewCosignVerifier(&other.PublicKey, &signer.PublicKey).Verify(signedRef, signedDigest, nil))
+
+	var verificationErr *errors.VerificationError
+	err = NewCosignVerifier(&other.PublicKey).Verify(signedRef, signedDigest, nil)
+	require.ErrorAs(t, err, &verificationErr)
+	require.Contains(t, verificationErr.Reason, "none of 1 signatures is valid")
+
+	err = NewCosignVerifier(&signer.PublicKey).Verify(unsignedRef, unsignedDigest, nil)
+	require.ErrorAs(t, err, &verificationErr)
+	require.Equal(t, "no signatures found", verificationErr.Reason)
+}
@@ -0,0 +1,32 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ExitStrategy waits until the container exited with ExitCode, any other exit code fails with an ExitedError
+type ExitStrategy struct {
+	ExitCode     int
+	PollInterval time.Duration
+}
+
+func ForExit(exitCode int) *ExitStrategy {
+	return &ExitStrategy{ExitCode: exitCode}
+}
+
+func (s *ExitStrategy) WaitUntilReady(ctx context.Context, client Client, id string) error {
+	return poll(ctx, s.PollInterval, func(ctx context.Context) error {
+		_, err := inspectRunning(ctx, client, id)
+		var exited *ExitedError
+		switch {
+		case errors.As(err, &exited) && exited.ExitCode == s.ExitCode:
+			return nil
+		case err != nil:
+			return err
+		default:
+			return notReady("container %s is still running", id)
+		}
+	})
+}
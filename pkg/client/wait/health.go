@@ -0,0 +1,34 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// HealthStrategy waits until the health check of the container reports healthy
+type HealthStrategy struct {
+	PollInterval time.Duration
+}
+
+func ForHealthy() *HealthStrategy {
+	return &HealthStrategy{}
+}
+
+func (s *HealthStrategy) WaitUntilReady(ctx context.Context, client Client, id string) error {
+	return poll(ctx, s.PollInterval, func(ctx context.Context) error {
+		inspect, err := inspectRunning(ctx, client, id)
+		if err != nil {
+			return err
+		}
+		if inspect.State.Health == nil || inspect.State.Health.Status == container.NoHealthcheck {
+			return fmt.Errorf("container %s has no health check", id)
+		}
+		if inspect.State.Health.Status != container.Healthy {
+			return notReady("container %s is %s", id, inspect.State.Health.Status)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds a single request
+const requestTimeout = 5 * time.Second
+
+// HTTPStrategy waits until a request to Path on the published Port returns StatusCode
+type HTTPStrategy struct {
+	// Port is the container port, e.g. "8080/tcp" or "8080"
+	Port         string
+	Path         string
+	Method       string
+	StatusCode   int
+	Probe        Probe
+	PollInterval time.Duration
+	// HTTPClient sends the requests, a client with a short timeout by default
+	HTTPClient *http.Client
+}
+
+// ForHTTP waits until a GET request to path on port returns 200 OK
+func ForHTTP(port, path string) *HTTPStrategy {
+	return &HTTPStrategy{Port: port, Path: path, Method: http.MethodGet, StatusCode: http.StatusOK}
+}
+
+// WithStatusCode waits for statusCode instead of 200 OK
+func (s *HTTPStrategy) WithStatusCode(statusCode int) *HTTPStrategy {
+	s.StatusCode = statusCode
+	return s
+}
+
+// WithMethod sends requests with method instead of GET
+func (s *HTTPStrategy) WithMethod(method string) *HTTPStrategy {
+	s.Method = method
+	return s
+}
+
+// WithProbe selects where the endpoint is requested from
+func (s *HTTPStrategy) WithProbe(probe Probe) *HTTPStrategy {
+	s.Probe = probe
+	return s
+}
+
+func (s *HTTPStrategy) WaitUntilReady(ctx context.Context, client Client, id string) error {
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: requestTimeout}
+	}
+	return poll(ctx, s.PollInterval, func(ctx context.Context) error {
+		address, err := PublishedAddress(ctx, client, id, s.Port, s.Probe)
+		if err != nil {
+			return err
+		}
+		endpoint := fmt.Sprintf("http://%s/%s", address, strings.TrimPrefix(s.Path, "/"))
+		req, err := http.NewRequestWithContext(ctx, s.Method, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return notReady("request to %s failed: %v", endpoint, err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != s.StatusCode {
+			return notReady("%s returned %d instead of %d", endpoint, resp.StatusCode, s.StatusCode)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,61 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/silenium-dev/docker-wrapper/pkg/client/logs"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+)
+
+// maxLogLineLength splits overlong log lines before matching them
+const maxLogLineLength = 1 << 20
+
+// LogStrategy waits until the logs (stdout and stderr) match Pattern Occurrences times
+type LogStrategy struct {
+	Pattern     *regexp.Regexp
+	Occurrences int
+}
+
+// ForLog waits for the first line matching pattern
+func ForLog(pattern *regexp.Regexp) *LogStrategy {
+	return &LogStrategy{Pattern: pattern, Occurrences: 1}
+}
+
+// WithOccurrences waits until pattern matched n times, e.g. for databases which restart during initialization
+func (s *LogStrategy) WithOccurrences(n int) *LogStrategy {
+	s.Occurrences = n
+	return s
+}
+
+func (s *LogStrategy) WaitUntilReady(ctx context.Context, client Client, id string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logStream, err := client.ContainerLogsStream(
+		ctx, id, logs.WithFollow(), logs.WithStreamOptions(stream.WithLines(maxLogLineLength)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to stream logs of container %s: %w", id, err)
+	}
+	defer func() { _ = logStream.Close() }()
+
+	matches := 0
+	for msg := range logStream.Messages() {
+		if msg.StreamType != stream.TypeStdout && msg.StreamType != stream.TypeStderr {
+			continue
+		}
+		matches += len(s.Pattern.FindAllIndex(msg.Content, -1))
+		if matches >= s.Occurrences {
+			return nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w, %d of %d occurrences of %q", err, matches, s.Occurrences, s.Pattern.String())
+	}
+	if _, err := inspectRunning(ctx, client, id); err != nil {
+		return err
+	}
+	return fmt.Errorf("logs of container %s ended after %d of %d occurrences of %q",
+		id, matches, s.Occurrences, s.Pattern.String())
+}
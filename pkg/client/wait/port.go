@@ -0,0 +1,130 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// dialTimeout bounds a single connection attempt
+const dialTimeout = time.Second
+
+// Probe selects where published ports are probed from
+type Probe int
+
+const (
+	// ProbeDaemonHost connects to the published port on the engine's host, as seen by the caller
+	ProbeDaemonHost Probe = iota
+	// ProbeHostIPFromContainers connects to the published port on the host IP as seen from containers
+	// (SystemHostIPFromContainers), e.g. if the caller itself runs in a container of the same engine
+	ProbeHostIPFromContainers
+)
+
+// PortStrategy waits until a TCP connection to the published Port succeeds.
+// Note that docker's userland proxy accepts connections before the process in the container listens.
+type PortStrategy struct {
+	// Port is the container port, e.g. "5432/tcp" or "5432"
+	Port         string
+	Probe        Probe
+	PollInterval time.Duration
+}
+
+func ForListeningPort(port string) *PortStrategy {
+	return &PortStrategy{Port: port}
+}
+
+// WithProbe selects where the port is probed from
+func (s *PortStrategy) WithProbe(probe Probe) *PortStrategy {
+	s.Probe = probe
+	return s
+}
+
+func (s *PortStrategy) WaitUntilReady(ctx context.Context, client Client, id string) error {
+	return poll(ctx, s.PollInterval, func(ctx context.Context) error {
+		address, err := PublishedAddress(ctx, client, id, s.Port, s.Probe)
+		if err != nil {
+			return err
+		}
+		dialer := net.Dialer{Timeout: dialTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return notReady("port %s of container %s is not reachable at %s: %v", s.Port, id, address, err)
+		}
+		_ = conn.Close()
+		return nil
+	})
+}
+
+// PublishedAddress returns the host:port the container port (e.g. "8080/tcp" or "8080") is reachable at from probe
+func PublishedAddress(ctx context.Context, client Client, id string, port string, probe Probe) (string, error) {
+	inspect, err := inspectRunning(ctx, client, id)
+	if err != nil {
+		return "", err
+	}
+	containerPort := nat.Port(port)
+	if !strings.Contains(port, "/") {
+		containerPort = nat.Port(port + "/tcp")
+	}
+	binding, ok := hostBinding(inspect, containerPort)
+	if !ok {
+		return "", notReady("port %s of container %s is not published", containerPort, id)
+	}
+
+	var host string
+	switch probe {
+	case ProbeHostIPFromContainers:
+		ip, err := client.SystemHostIPFromContainers(ctx, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to get host IP: %w", err)
+		}
+		host = ip.String()
+	default:
+		host = daemonHost(client.DaemonHost())
+		if host == "localhost" && !isUnspecified(binding.HostIP) {
+			host = binding.HostIP
+		}
+	}
+	return net.JoinHostPort(host, binding.HostPort), nil
+}
+
+// hostBinding returns the first binding of port, preferring IPv4
+func hostBinding(inspect container.InspectResponse, port nat.Port) (nat.PortBinding, bool) {
+	if inspect.NetworkSettings == nil {
+		return nat.PortBinding{}, false
+	}
+	bindings := inspect.NetworkSettings.Ports[port]
+	for _, binding := range bindings {
+		if ip := net.ParseIP(binding.HostIP); binding.HostIP == "" || ip.To4() != nil {
+			return binding, binding.HostPort != ""
+		}
+	}
+	if len(bindings) > 0 {
+		return bindings[0], bindings[0].HostPort != ""
+	}
+	return nat.PortBinding{}, false
+}
+
+func isUnspecified(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed == nil || parsed.IsUnspecified()
+}
+
+// daemonHost returns the host name of a remote engine (tcp://, ssh://, http(s)://), localhost for local sockets
+func daemonHost(daemonHost string) string {
+	parsed, err := url.Parse(daemonHost)
+	if err != nil || parsed.Hostname() == "" {
+		return "localhost"
+	}
+	switch parsed.Scheme {
+	case "tcp", "ssh", "http", "https":
+		return parsed.Hostname()
+	default:
+		return "localhost"
+	}
+}
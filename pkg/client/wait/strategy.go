@@ -0,0 +1,157 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/logs"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultPollInterval is the interval strategies without an interval of their own poll the engine with
+const DefaultPollInterval = 100 * time.Millisecond
+
+// Client is the part of the wrapper the strategies use
+type Client interface {
+	ContainerInspect(ctx context.Context, id string) (container.InspectResponse, error)
+	ContainerLogsStream(ctx context.Context, id string, opts ...logs.Opt) (*stream.MultiplexedStream, error)
+	SystemHostIPFromContainers(ctx context.Context, netId *string) (net.IP, error)
+	DaemonHost() string
+}
+
+// Strategy decides when a container is ready
+type Strategy interface {
+	// WaitUntilReady blocks until the container is ready, it fails if the container can't become ready anymore
+	WaitUntilReady(ctx context.Context, client Client, id string) error
+}
+
+// ExitedError is returned if the container exited before it became ready
+type ExitedError struct {
+	ID       string
+	ExitCode int
+}
+
+func (e *ExitedError) Error() string {
+	return fmt.Sprintf("container %s exited with code %d", e.ID, e.ExitCode)
+}
+
+// notReadyError is a failed check, which is retried until the context is done
+type notReadyError struct {
+	reason error
+}
+
+func (e *notReadyError) Error() string {
+	return e.reason.Error()
+}
+
+func notReady(format string, args ...any) error {
+	return &notReadyError{reason: fmt.Errorf(format, args...)}
+}
+
+// poll runs check until it succeeds or fails with an error other than notReady
+func poll(ctx context.Context, interval time.Duration, check func(ctx context.Context) error) error {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := check(ctx)
+		var notReadyErr *notReadyError
+		if err == nil || !errors.As(err, &notReadyErr) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w, last check: %v", ctx.Err(), notReadyErr.reason)
+		case <-ticker.C:
+		}
+	}
+}
+
+// inspectRunning inspects the container and fails if it is not running anymore
+func inspectRunning(ctx context.Context, client Client, id string) (container.InspectResponse, error) {
+	inspect, err := client.ContainerInspect(ctx, id)
+	if err != nil {
+		return inspect, fmt.Errorf("failed to inspect container %s: %w", id, err)
+	}
+	if inspect.State == nil {
+		return inspect, notReady("container %s has no state", id)
+	}
+	switch inspect.State.Status {
+	case container.StateExited, container.StateDead:
+		return inspect, &ExitedError{ID: id, ExitCode: inspect.State.ExitCode}
+	case container.StateRunning:
+		return inspect, nil
+	default:
+		return inspect, notReady("container %s is %s", id, inspect.State.Status)
+	}
+}
+
+type timeoutStrategy struct {
+	Strategy
+	timeout time.Duration
+}
+
+// WithTimeout fails strategy if it is not ready within timeout
+func WithTimeout(strategy Strategy, timeout time.Duration) Strategy {
+	return &timeoutStrategy{Strategy: strategy, timeout: timeout}
+}
+
+func (s *timeoutStrategy) WaitUntilReady(ctx context.Context, client Client, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.Strategy.WaitUntilReady(ctx, client, id)
+}
+
+type allStrategy []Strategy
+
+// All is ready once all strategies are ready, it fails as soon as one of them fails
+func All(strategies ...Strategy) Strategy {
+	return allStrategy(strategies)
+}
+
+func (s allStrategy) WaitUntilReady(ctx context.Context, client Client, id string) error {
+	group, ctx := errgroup.WithContext(ctx)
+	for _, strategy := range s {
+		group.Go(func() error {
+			return strategy.WaitUntilReady(ctx, client, id)
+		})
+	}
+	return group.Wait()
+}
+
+type anyStrategy []Strategy
+
+// Any is ready once one of the strategies is ready, it fails if all of them fail
+func Any(strategies ...Strategy) Strategy {
+	return anyStrategy(strategies)
+}
+
+func (s anyStrategy) WaitUntilReady(ctx context.Context, client Client, id string) error {
+	if len(s) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan error, len(s))
+	for _, strategy := range s {
+		go func() {
+			results <- strategy.WaitUntilReady(ctx, client, id)
+		}()
+	}
+	var errs []error
+	for range s {
+		err := <-results
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,149 @@
+package wait
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/logs"
+	"github.com/silenium-dev/docker-wrapper/pkg/client/stream"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeClient returns the next of states on every inspect, the last one repeatedly
+type fakeClient struct {
+	mutex  sync.Mutex
+	states []container.State
+	ports  nat.PortMap
+	logs   string
+}
+
+func (f *fakeClient) ContainerInspect(context.Context, string) (container.InspectResponse, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	state := f.states[0]
+	if len(f.states) > 1 {
+		f.states = f.states[1:]
+	}
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{State: &state},
+		NetworkSettings:   &container.NetworkSettings{NetworkSettingsBase: container.NetworkSettingsBase{Ports: f.ports}},
+	}, nil
+}
+
+func (f *fakeClient) ContainerLogsStream(ctx context.Context, _ string, opts ...logs.Opt) (
+	*stream.MultiplexedStream, error,
+) {
+	options := logs.RenderOptions(opts)
+	return stream.NewMultiplexedStream(
+		ctx, strings.NewReader(f.logs), nil, nil, false, zap.NewNop().Sugar(), options.Stream...,
+	), nil
+}
+
+func (f *fakeClient) SystemHostIPFromContainers(context.Context, *string) (net.IP, error) {
+	return net.IPv4(127, 0, 0, 1), nil
+}
+
+func (f *fakeClient) DaemonHost() string {
+	return "unix:///var/run/docker.sock"
+}
+
+func running(health container.HealthStatus) container.State {
+	state := container.State{Status: container.StateRunning, Running: true}
+	if health != "" {
+		state.Health = &container.Health{Status: health}
+	}
+	return state
+}
+
+func TestLogStrategy(t *testing.T) {
+	client := &fakeClient{
+		states: []container.State{running("")},
+		logs:   "starting\nready to accept connections\nrestarting\nready to accept connections\n",
+	}
+	ctx := context.Background()
+	pattern := regexp.MustCompile("ready to accept connections")
+	require.NoError(t, ForLog(pattern).WithOccurrences(2).WaitUntilReady(ctx, client, "id"))
+	require.ErrorContains(t, ForLog(pattern).WithOccurrences(3).WaitUntilReady(ctx, client, "id"), "2 of 3")
+}
+
+func TestHealthStrategy(t *testing.T) {
+	client := &fakeClient{states: []container.State{
+		{Status: container.StateCreated}, running(container.Starting), running(container.Healthy),
+	}}
+	strategy := &HealthStrategy{PollInterval: time.Millisecond}
+	require.NoError(t, strategy.WaitUntilReady(context.Background(), client, "id"))
+
+	client = &fakeClient{states: []container.State{running("")}}
+	require.ErrorContains(t, strategy.WaitUntilReady(context.Background(), client, "id"), "no health check")
+}
+
+func TestExitStrategy(t *testing.T) {
+	exited := container.State{Status: container.StateExited, ExitCode: 1}
+	client := &fakeClient{states: []container.State{running(""), exited}}
+	strategy := &ExitStrategy{ExitCode: 1, PollInterval: time.Millisecond}
+	require.NoError(t, strategy.WaitUntilReady(context.Background(), client, "id"))
+
+	var exitedErr *ExitedError
+	strategy.ExitCode = 0
+	require.ErrorAs(t, strategy.WaitUntilReady(context.Background(), client, "id"), &exitedErr)
+	require.Equal(t, 1, exitedErr.ExitCode)
+}
+
+func TestHTTPAndPortStrategy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	client := &fakeClient{
+		states: []container.State{running("")},
+		ports:  nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: port}}},
+	}
+
+	ctx := context.Background()
+	require.NoError(t, ForListeningPort("80").WaitUntilReady(ctx, client, "id"))
+	health := ForHTTP("80/tcp", "/health").WithProbe(ProbeHostIPFromContainers)
+	require.NoError(t, health.WaitUntilReady(ctx, client, "id"))
+	require.NoError(t, ForHTTP("80", "missing").WithStatusCode(http.StatusNotFound).WaitUntilReady(ctx, client, "id"))
+
+	err = WithTimeout(ForListeningPort("81"), 50*time.Millisecond).WaitUntilReady(ctx, client, "id")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "not published")
+}
+
+func TestCombinators(t *testing.T) {
+	client := &fakeClient{states: []container.State{running(container.Healthy)}, logs: "ready\n"}
+	ctx := context.Background()
+	ready := ForLog(regexp.MustCompile("ready"))
+	never := WithTimeout(ForLog(regexp.MustCompile("never")), 50*time.Millisecond)
+
+	require.NoError(t, All(ready, ForHealthy()).WaitUntilReady(ctx, client, "id"))
+	require.Error(t, All(ready, never).WaitUntilReady(ctx, client, "id"))
+	require.NoError(t, Any(never, ready).WaitUntilReady(ctx, client, "id"))
+	// the container keeps running, so it never exits
+	require.Error(t, Any(never, WithTimeout(ForExit(0), 50*time.Millisecond)).WaitUntilReady(ctx, client, "id"))
+}
+
+func TestDaemonHost(t *testing.T) {
+	for host, expected := range map[string]string{
+		"unix:///var/run/docker.sock": "localhost",
+		"npipe:////./pipe/docker":     "localhost",
+		"tcp://10.0.0.1:2376":         "10.0.0.1",
+		"ssh://user@remote":           "remote",
+	} {
+		require.Equal(t, expected, daemonHost(host), host)
+	}
+}
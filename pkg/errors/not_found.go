@@ -13,6 +13,7 @@ const (
 	ResourceTypeContainer = "container"
 	ResourceTypeVolume    = "volume"
 	ResourceTypeImage     = "image"
+	ResourceTypeNetwork   = "network"
 )
 
 func IsNotFound(err error, resource ResourceType) bool {
@@ -25,3 +26,20 @@ func IsNotFound(err error, resource ResourceType) bool {
 
 	return strings.Contains(strings.ToLower(err.Error()), fmt.Sprintf("no such %s", resource))
 }
+
+// NotFoundError is returned by the wrapper when a resource is missing locally
+type NotFoundError struct {
+	Resource ResourceType
+	ID       string
+	Reason   string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("no such %s: %s", e.Resource, e.ID)
+	}
+	return fmt.Sprintf("no such %s: %s (%s)", e.Resource, e.ID, e.Reason)
+}
+
+// NotFound marks the error as not-found for errdefs.IsNotFound
+func (e *NotFoundError) NotFound() {}
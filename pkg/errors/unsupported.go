@@ -0,0 +1,16 @@
+package errors
+
+import "fmt"
+
+// UnsupportedError is returned when the engine does not support a requested feature
+type UnsupportedError struct {
+	Feature string
+	Engine  string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported by %s", e.Feature, e.Engine)
+}
+
+// NotImplemented marks the error as not-implemented for errdefs.IsNotImplemented
+func (e *UnsupportedError) NotImplemented() {}
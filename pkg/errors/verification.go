@@ -0,0 +1,14 @@
+package errors
+
+import "fmt"
+
+// VerificationError is returned when an image failed signature verification before a pull
+type VerificationError struct {
+	Ref    string
+	Digest string
+	Reason string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("verification of %s@%s failed: %s", e.Ref, e.Digest, e.Reason)
+}